@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/emilosman/rssboat/internal/daemon"
+	"github.com/emilosman/rssboat/internal/rss"
+)
+
+// How often urls.yaml is checked for changes and changes are saved
+const (
+	daemonWatchInterval = 2 * time.Second
+	daemonSaveInterval  = 5 * time.Second
+)
+
+// runDaemon keeps the list in one process that refreshes feeds on schedule
+// and serves it on a Unix socket. The app and commands attach to it while
+// it runs, so several of them share one list.
+func runDaemon(args []string) error {
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	refresh := fs.Duration("refresh", 30*time.Minute, "how often feeds that are due are refreshed, 0 to disable")
+	socket := fs.String("socket", "", "socket path, defaults to $XDG_RUNTIME_DIR/rssboat.sock or the cache dir")
+	parseArgs(fs, args)
+
+	if _, err := urlsPath(); err != nil {
+		return errors.New("rssboat daemon serves the feeds of urls.yaml, unset RSSBOAT_MINIFLUX_URL and RSSBOAT_NEXTCLOUD_URL")
+	}
+
+	configFilePath, err := rss.ConfigFilePath()
+	if err != nil {
+		return &loadError{err}
+	}
+	filesystem := os.DirFS(configFilePath)
+	l, err := rss.LoadList(filesystem)
+	if errors.Is(err, rss.ErrInclude) {
+		log.Println(err)
+	} else if err != nil {
+		return &loadError{err}
+	}
+
+	s := daemon.NewServer(l)
+	s.Save = l.SaveCache
+	s.Urls = func() ([]*rss.RssFeed, error) {
+		return rss.ReadUrls(filesystem, "urls.yaml")
+	}
+
+	path := *socket
+	if path == "" {
+		if path, err = daemon.SocketPath(); err != nil {
+			return err
+		}
+	}
+	ln, err := daemon.Listen(path)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	go s.Serve(ln)
+	log.Printf("Listening on %s", path)
+
+	if *refresh > 0 {
+		go func() {
+			for {
+				reply, err := s.Refresh(daemon.RefreshArgs{})
+				if err != nil && !errors.Is(err, rss.ErrNoFeedsInList) {
+					log.Println("Error refreshing feeds:", err)
+				} else if reply.Feeds > 0 {
+					log.Printf("Refreshed %d feeds, %d new items, %d failed", reply.Feeds, reply.New, len(reply.Errors))
+				}
+				time.Sleep(*refresh)
+			}
+		}()
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	watch := time.NewTicker(daemonWatchInterval)
+	defer watch.Stop()
+	save := time.NewTicker(daemonSaveInterval)
+	defer save.Stop()
+
+	urlsFile := filepath.Join(configFilePath, "urls.yaml")
+	urlsMod := modTime(urlsFile)
+	for {
+		select {
+		case <-stop:
+			return s.Flush()
+		case <-save.C:
+			if err := s.Flush(); err != nil {
+				log.Println("Error saving:", err)
+			}
+		case <-watch.C:
+			if mod := modTime(urlsFile); !mod.Equal(urlsMod) {
+				urlsMod = mod
+				go func() {
+					if err := s.Reconcile(); err != nil {
+						log.Println("Error reloading urls.yaml:", err)
+					} else {
+						log.Println("Reloaded urls.yaml")
+					}
+				}()
+			}
+		}
+	}
+}
+
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/emilosman/rssboat/internal/config"
+	"github.com/emilosman/rssboat/internal/digest"
+	"github.com/emilosman/rssboat/internal/rss"
+)
+
+// sendDigest emails the new unread items, grouped by category and feed, or
+// writes the email to a file
+func sendDigest(args []string) error {
+	fs := flag.NewFlagSet("digest", flag.ExitOnError)
+	since := fs.String("since", "24h", "only items published or fetched within this long, e.g. 24h or 7d")
+	category := fs.String("category", "", "only items of this category and its nested categories")
+	tag := fs.String("tag", "", "only items of feeds with this tag")
+	all := fs.Bool("all", false, "also include read items")
+	markRead := fs.Bool("mark-read", false, "mark the items in the digest read once it is sent")
+	to := fs.String("to", "", "comma separated addresses replacing digest.to of config.yaml")
+	output := fs.String("o", "", "write the email to a file, such as digest.eml, or - for stdout, instead of sending it")
+	parseArgs(fs, args)
+
+	d, err := rss.ParseDuration(*since)
+	if err != nil {
+		return err
+	}
+	q := rss.ItemQuery{Category: *category, Tag: *tag, Unread: !*all, Since: time.Now().Add(-d)}
+
+	cfg, err := config.LoadUserConfig()
+	if err != nil {
+		return err
+	}
+	recipients := cfg.Digest.To
+	if *to != "" {
+		recipients = strings.Split(*to, ",")
+		for i := range recipients {
+			recipients[i] = strings.TrimSpace(recipients[i])
+		}
+	}
+	from := cfg.Digest.From
+	if from == "" && len(recipients) > 0 {
+		from = recipients[0]
+	}
+
+	l, err := loadList()
+	if err != nil {
+		return err
+	}
+	dg, err := digest.New(l, q)
+	if err != nil {
+		return err
+	}
+	if dg.Count == 0 {
+		fmt.Fprintln(os.Stderr, "No new items, no digest written")
+		return nil
+	}
+	dg.DateFormat = cfg.Display.DateFormat
+
+	if from == "" {
+		from = "rssboat@localhost"
+	}
+	msg, err := dg.Message(from, recipients)
+	if err != nil {
+		return err
+	}
+
+	switch *output {
+	case "":
+		if err := digest.Send(cfg.Digest.SMTP, from, recipients, msg); err != nil {
+			return err
+		}
+		fmt.Printf("Sent the digest to %s\n", strings.Join(recipients, ", "))
+	case "-":
+		if _, err := os.Stdout.Write(msg); err != nil {
+			return err
+		}
+	default:
+		if err := os.WriteFile(*output, msg, 0644); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote the digest to %s\n", *output)
+	}
+
+	if !*markRead {
+		return nil
+	}
+	dg.MarkRead()
+
+	// Pushed before saving, as in mark-read
+	pushErr := l.PushChanges()
+	if err := l.SaveCache(); err != nil {
+		return err
+	}
+	return pushErr
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/emilosman/rssboat/internal/rss"
+)
+
+// list prints feeds as tab separated lines, or items like rssboat items --format tsv
+func list(args []string) error {
+	if len(args) > 0 && args[0] == "items" {
+		return items(append([]string{"--format", "tsv"}, args[1:]...))
+	}
+
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	category := fs.String("category", "", "only list this category and its nested categories")
+	unread := fs.Bool("unread", false, "only list feeds with unread items")
+	what := parseArgs(fs, args)
+	if len(what) != 1 || what[0] != "feeds" {
+		return fmt.Errorf("usage: rssboat list feeds|items [--category c] [--unread]")
+	}
+
+	l, err := loadList()
+	if err != nil {
+		return err
+	}
+
+	feeds := subscribed(l)
+	if *category != "" {
+		if feeds, err = categoryFeeds(l, []string{*category}); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range feeds {
+		if *unread && !f.HasUnread() {
+			continue
+		}
+		fmt.Printf("%d\t%d\t%s\t%s\t%s\n", f.UnreadCount(), len(f.RssItems), strings.Join(f.Categories, ","), f.Name(), f.Url)
+	}
+	return nil
+}
+
+// add adds a feed to urls.yaml
+func add(args []string) error {
+	fs := flag.NewFlagSet("add", flag.ExitOnError)
+	category := fs.String("category", "", "category to add the feed to, created if needed")
+	title := fs.String("title", "", "title replacing the feed's own")
+	urls := parseArgs(fs, args)
+	if len(urls) != 1 || *category == "" {
+		return fmt.Errorf("usage: rssboat add <url> --category c [--title t]")
+	}
+
+	path, err := urlsPath()
+	if err != nil {
+		return err
+	}
+
+	feed := &rss.RssFeed{Url: urls[0], Category: *category, Options: rss.FeedOptions{Title: *title}}
+	added, err := rss.AddToUrlsFile(path, []*rss.RssFeed{feed})
+	if err != nil {
+		return err
+	}
+	if len(added) == 0 {
+		return fmt.Errorf("%s is already in urls.yaml", feed.Url)
+	}
+
+	fmt.Printf("Added %s to %s\n", feed.Url, feed.Category)
+	return nil
+}
+
+// remove removes a feed from urls.yaml, from one category or all of them
+func remove(args []string) error {
+	fs := flag.NewFlagSet("remove", flag.ExitOnError)
+	category := fs.String("category", "", "only remove the feed from this category")
+	urls := parseArgs(fs, args)
+	if len(urls) != 1 {
+		return fmt.Errorf("usage: rssboat remove <url> [--category c]")
+	}
+	url := urls[0]
+
+	path, err := urlsPath()
+	if err != nil {
+		return err
+	}
+
+	categories := []string{*category}
+	if *category == "" {
+		if categories, err = urlCategories(path, url); err != nil {
+			return err
+		}
+	}
+
+	err = rss.EditUrlsFile(path, func(filename string, data []byte) ([]byte, error) {
+		for _, c := range categories {
+			if data, err = rss.RemoveFromUrls(filename, data, c, url); err != nil {
+				return nil, err
+			}
+		}
+		return data, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed %s from %s\n", url, strings.Join(categories, ", "))
+	return nil
+}
+
+// urlCategories lists the categories of urls.yaml, leaving out includes,
+// that list url
+func urlCategories(path, url string) ([]string, error) {
+	feeds, err := rss.ReadUrls(os.DirFS(filepath.Dir(path)), filepath.Base(path))
+	if err != nil && !errors.Is(err, rss.ErrInclude) {
+		return nil, err
+	}
+
+	var categories []string
+	for _, f := range feeds {
+		if f.Url == url && f.Source == "" {
+			categories = append(categories, f.Category)
+		}
+	}
+	if len(categories) == 0 {
+		return nil, fmt.Errorf("%w: %s", rss.ErrFeedNotFound, url)
+	}
+	return categories, nil
+}
+
+// markRead marks the items of every feed, a category or the given feeds read
+func markRead(args []string) error {
+	fs := flag.NewFlagSet("mark-read", flag.ExitOnError)
+	all := fs.Bool("all", false, "mark every feed read")
+	category := fs.String("category", "", "mark a category and its nested categories read")
+	urls := parseArgs(fs, args)
+
+	l, err := loadList()
+	if err != nil {
+		return err
+	}
+
+	var feeds []*rss.RssFeed
+	switch {
+	case *all:
+		feeds = subscribed(l)
+	case *category != "":
+		if feeds, err = categoryFeeds(l, []string{*category}); err != nil {
+			return err
+		}
+	case len(urls) > 0:
+		for _, url := range urls {
+			feed, ok := l.FeedIndex[url]
+			if !ok {
+				return fmt.Errorf("%w: %s", rss.ErrFeedNotFound, url)
+			}
+			feeds = append(feeds, feed)
+		}
+	default:
+		return fmt.Errorf("usage: rssboat mark-read --all | --category c | <feed url...>")
+	}
+
+	count := 0
+	for _, f := range feeds {
+		count += f.UnreadCount()
+	}
+	rss.MarkFeedsAsRead(feeds...)
+
+	// Pushed before saving so the cache has them as synced, changes that
+	// fail to push are saved as pending for the next run
+	pushErr := l.PushChanges()
+	if err := l.SaveCache(); err != nil {
+		return err
+	}
+	if pushErr != nil {
+		return pushErr
+	}
+
+	fmt.Printf("Marked %d items read\n", count)
+	return nil
+}
+
+// subscribed returns every feed but bookmarks and hidden feeds
+func subscribed(l *rss.List) []*rss.RssFeed {
+	var feeds []*rss.RssFeed
+	for _, f := range l.Feeds {
+		if f != l.Bookmarks() && !f.Options.Hidden {
+			feeds = append(feeds, f)
+		}
+	}
+	return feeds
+}
+
+// categoryFeeds returns the feeds of categories, each once
+func categoryFeeds(l *rss.List, categories []string) ([]*rss.RssFeed, error) {
+	var feeds []*rss.RssFeed
+	for _, category := range categories {
+		if !slices.Contains(l.Categories(), category) {
+			return nil, fmt.Errorf("%w: %s", rss.ErrCategoryNotFound, category)
+		}
+		found, err := l.GetCategory(category)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range found {
+			if !slices.Contains(feeds, f) {
+				feeds = append(feeds, f)
+			}
+		}
+	}
+	return feeds, nil
+}
+
+// oneLine keeps titles with line breaks or tabs from breaking the columns
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/emilosman/rssboat/internal/config"
+	"github.com/emilosman/rssboat/internal/newsboat"
+	"github.com/emilosman/rssboat/internal/opml"
+	"github.com/emilosman/rssboat/internal/rss"
+	"github.com/emilosman/rssboat/internal/tui"
+)
+
+// importFeeds adds the feeds of an OPML file or Newsboat's urls file to
+// urls.yaml
+func importFeeds(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: rssboat import opml|newsboat")
+	}
+
+	switch args[0] {
+	case "opml":
+		return importOpml(args[1:])
+	case "newsboat":
+		return importNewsboat(args[1:])
+	}
+	return fmt.Errorf("usage: rssboat import opml|newsboat")
+}
+
+func importOpml(args []string) error {
+	fs := flag.NewFlagSet("import opml", flag.ExitOnError)
+	flatten := fs.Bool("flatten", false, "put feeds of nested folders in their top folder")
+	files := parseArgs(fs, args)
+	if len(files) != 1 {
+		return fmt.Errorf("usage: rssboat import opml [--flatten] file.opml")
+	}
+
+	f, err := os.Open(files[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	feeds, err := opml.Parse(f, *flatten)
+	if err != nil {
+		return err
+	}
+
+	path, err := urlsPath()
+	if err != nil {
+		return err
+	}
+	added, err := rss.AddToUrlsFile(path, feeds)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %d of %d feeds, the rest were already in urls.yaml\n", len(added), len(feeds))
+	return nil
+}
+
+// importNewsboat adds Newsboat's feeds to urls.yaml and carries the read and
+// flagged state of its cache over to rssboat's cache
+func importNewsboat(args []string) error {
+	defaultUrls, defaultCache := newsboat.DefaultPaths()
+
+	fs := flag.NewFlagSet("import newsboat", flag.ExitOnError)
+	newsboatUrls := fs.String("urls", defaultUrls, "Newsboat urls file")
+	cachePath := fs.String("cache", defaultCache, "Newsboat cache.db, empty to skip read state")
+	parseArgs(fs, args)
+
+	path, err := urlsPath()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(*newsboatUrls)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	feeds, skipped, err := newsboat.ParseUrls(f)
+	if err != nil {
+		return err
+	}
+
+	added, err := rss.AddToUrlsFile(path, feeds)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %d of %d feeds, the rest were already in urls.yaml\n", len(added), len(feeds))
+	for _, url := range skipped {
+		fmt.Println("Skipped", url)
+	}
+
+	if *cachePath == "" {
+		return nil
+	}
+	items, err := newsboat.ReadCache(*cachePath)
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Println("No cache at", *cachePath+", read state not imported")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Feeds of an include that can't be read are left out, as in loadList
+	l, err := rss.LoadList(os.DirFS(filepath.Dir(path)))
+	if errors.Is(err, rss.ErrInclude) {
+		fmt.Fprintln(os.Stderr, err)
+	} else if err != nil {
+		return &loadError{err}
+	}
+	matched, restored := newsboat.ApplyCache(l, items)
+	if err := l.SaveCache(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Carried over read state of %d items, added %d items rssboat hadn't fetched\n", matched, restored)
+	return nil
+}
+
+// exportFeeds writes the feeds as OPML
+func exportFeeds(args []string) error {
+	if len(args) == 0 || args[0] != "opml" {
+		return fmt.Errorf("usage: rssboat export opml [-o file.opml]")
+	}
+
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	output := fs.String("o", "", "write to a file instead of stdout")
+	parseArgs(fs, args[1:])
+
+	l, err := loadList()
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		return opml.Export(os.Stdout, l)
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return opml.Export(f, l)
+}
+
+// check lints urls.yaml and config.yaml, and with --online fetches every
+// feed. It fails when anything is wrong, so it can run in CI.
+func check(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	online := fs.Bool("online", false, "also fetch every feed")
+	parseArgs(fs, args)
+
+	configFilePath, err := rss.ConfigFilePath()
+	if err != nil {
+		return &loadError{err}
+	}
+	dir := os.DirFS(configFilePath)
+
+	problems, err := rss.CheckUrlsFile(dir, "urls.yaml")
+	if err != nil {
+		return err
+	}
+	count := len(problems)
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+
+	cfg, err := config.Load(dir, config.FileName)
+	if err != nil {
+		fmt.Println(err)
+		count++
+	} else if err := tui.CheckKeys(cfg.Keys); err != nil {
+		fmt.Printf("%s: %v\n", config.FileName, err)
+		count++
+	}
+
+	if *online {
+		l, err := rss.LoadList(dir)
+		if err != nil {
+			return &loadError{err}
+		}
+		var feeds []*rss.RssFeed
+		for _, f := range l.Feeds {
+			if f != l.Bookmarks() {
+				feeds = append(feeds, f)
+			}
+		}
+		count += checkFeeds(feeds)
+	}
+
+	if count > 0 {
+		return fmt.Errorf("problems found: %d", count)
+	}
+	fmt.Println("No problems found")
+	return nil
+}
+
+// checkFeeds probes feeds a few at a time and prints a line for each in order
+func checkFeeds(feeds []*rss.RssFeed) int {
+	reports := make([]chan rss.FeedReport, len(feeds))
+	limit := make(chan struct{}, 8)
+	for i, f := range feeds {
+		reports[i] = make(chan rss.FeedReport, 1)
+		go func() {
+			limit <- struct{}{}
+			reports[i] <- rss.ProbeFeed(f)
+			<-limit
+		}()
+	}
+
+	count := 0
+	for _, report := range reports {
+		r := <-report
+		if len(r.Problems) == 0 {
+			fmt.Printf("ok    %s (%s, %s, %d items)\n", r.Feed.Url, r.Status, r.ContentType, r.Items)
+			continue
+		}
+		for _, problem := range r.Problems {
+			fmt.Printf("FAIL  %s: %v\n", r.Feed.Url, problem)
+		}
+		count += len(r.Problems)
+	}
+	return count
+}
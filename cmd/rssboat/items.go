@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emilosman/rssboat/internal/rss"
+)
+
+var itemColumns = []string{"feed", "feed_url", "key", "category", "title", "link", "author", "published", "updated", "first_seen", "read_at", "read", "bookmark"}
+
+// items prints the items of the cache one per line, for jq, fzf and the
+// like. Like status it doesn't use the network.
+func items(args []string) error {
+	fs := flag.NewFlagSet("items", flag.ExitOnError)
+	category := fs.String("category", "", "only items of this category and its nested categories")
+	feedUrl := fs.String("feed", "", "only items of this feed")
+	unread := fs.Bool("unread", false, "only unread items")
+	bookmarked := fs.Bool("bookmarked", false, "only bookmarked items")
+	tag := fs.String("tag", "", "only items of feeds with this tag")
+	since := fs.String("since", "", "only items published or fetched within this long, e.g. 24h or 7d")
+	format := fs.String("format", "jsonl", "jsonl, csv or tsv")
+	content := fs.Bool("content", false, "include the content of items")
+	parseArgs(fs, args)
+
+	q := rss.ItemQuery{Category: *category, Feed: *feedUrl, Unread: *unread, Bookmarked: *bookmarked, Tag: *tag}
+	if *since != "" {
+		d, err := rss.ParseDuration(*since)
+		if err != nil {
+			return err
+		}
+		q.Since = time.Now().Add(-d)
+	}
+
+	var write func(rss.ItemRecord) error
+	flush := func() error { return nil }
+	switch *format {
+	case "jsonl":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		write = func(r rss.ItemRecord) error { return enc.Encode(r) }
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		header := itemColumns
+		if *content {
+			header = append(header, "content")
+		}
+		w.Write(header)
+		write = func(r rss.ItemRecord) error { return w.Write(recordFields(r, *content)) }
+		flush = func() error { w.Flush(); return w.Error() }
+	case "tsv":
+		write = func(r rss.ItemRecord) error {
+			fields := recordFields(r, *content)
+			for i := range fields {
+				fields[i] = oneLine(fields[i])
+			}
+			_, err := fmt.Println(strings.Join(fields, "\t"))
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown format %q, use jsonl, csv or tsv", *format)
+	}
+
+	l, err := loadCachedList()
+	if err != nil {
+		return err
+	}
+	found, err := l.Items(q)
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range found {
+		if err := write(fi.Record(*content)); err != nil {
+			return err
+		}
+	}
+	return flush()
+}
+
+// recordFields returns a record's fields in the order of itemColumns
+func recordFields(r rss.ItemRecord, content bool) []string {
+	date := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	fields := []string{
+		r.Feed, r.FeedUrl, r.Key, r.Category, r.Title, r.Link, r.Author,
+		date(r.Published), date(r.Updated), date(r.FirstSeen), date(r.ReadAt),
+		strconv.FormatBool(r.Read), strconv.FormatBool(r.Bookmark),
+	}
+	if content {
+		fields = append(fields, r.Content)
+	}
+	return fields
+}
@@ -1,9 +1,192 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"text/tabwriter"
+
+	"github.com/emilosman/rssboat/internal/rss"
 	"github.com/emilosman/rssboat/internal/tui"
 )
 
+// version is set at build time with -ldflags "-X main.version=v1.2.3"
+var version = ""
+
+// Exit codes, so scripts can tell feeds that failed from a config that
+// doesn't load
+const (
+	exitFailed = 1
+	exitConfig = 2
+)
+
+type command struct {
+	name, args, desc string
+	run              func(args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"tui", "", "open the app (default)", runTui},
+		{"refresh", "[--all] [--quiet] [--json] [category...]", "fetch feeds that are due, or every feed", refresh},
+		{"status", "[--format text|json|template]", "print unread counts from the cache, for status bars", status},
+		{"list", "feeds|items [--category c] [--unread]", "print feeds or items as tab separated lines", list},
+		{"items", "[--category c] [--unread] [--since 24h] [--format jsonl|csv|tsv]", "print items for scripts", items},
+		{"search", "<query> [--category c] [--feed url] [--unread] [--bookmarked]", "search cached items, best matches first", search},
+		{"digest", "[--since 24h] [--category c] [--mark-read] [--to a@b] [-o file.eml]", "email new items, or write the email to a file", sendDigest},
+		{"add", "<url> --category c [--title t]", "add a feed to urls.yaml", add},
+		{"remove", "<url> [--category c]", "remove a feed from urls.yaml", remove},
+		{"mark-read", "--all | --category c | <feed url...>", "mark items read", markRead},
+		{"import", "opml|newsboat", "add feeds from OPML or Newsboat", importFeeds},
+		{"export", "opml [-o file.opml]", "write the feeds as OPML", exportFeeds},
+		{"check", "[--online]", "lint urls.yaml and config.yaml, and fetch every feed", check},
+		{"daemon", "[--refresh 30m] [--socket path]", "keep the feeds in one process the app and commands attach to", runDaemon},
+		{"serve", "--fever", "serve the Fever API for mobile apps", serve},
+		{"version", "", "print the version", printVersion},
+		{"help", "", "show this help", printUsage},
+	}
+}
+
 func main() {
+	name, args := "tui", os.Args[1:]
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	if name == "-h" || name == "--help" {
+		name = "help"
+	}
+
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		if err := c.run(args); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(exitCode(err))
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Error: unknown command %q\n\n", name)
+	printUsage(nil)
+	os.Exit(exitConfig)
+}
+
+func exitCode(err error) int {
+	var (
+		configErr *rss.ConfigError
+		loadErr   *loadError
+	)
+	switch {
+	case errors.As(err, &loadErr), errors.Is(err, rss.ErrCategoryNotFound):
+		return exitConfig
+	case errors.As(err, &configErr) && !errors.Is(err, rss.ErrInclude):
+		return exitConfig
+	}
+	return exitFailed
+}
+
+// loadError is an error loading the config dir, urls.yaml, config.yaml or
+// the cache, as opposed to a feed failing
+type loadError struct {
+	err error
+}
+
+func (e *loadError) Error() string { return e.err.Error() }
+func (e *loadError) Unwrap() error { return e.err }
+
+func runTui(args []string) error {
 	tui.BuildApp()
+	return nil
+}
+
+func printUsage(args []string) error {
+	fmt.Println("Usage: rssboat [command]")
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %s %s\t%s\n", c.name, c.args, c.desc)
+	}
+	return w.Flush()
+}
+
+func printVersion(args []string) error {
+	v := version
+	if v == "" {
+		v = "dev"
+		if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			v = info.Main.Version
+		}
+	}
+	fmt.Println("rssboat", v)
+	return nil
+}
+
+// parseArgs parses flags given before, between or after positional arguments,
+// which the flag package stops at, and returns the positional ones
+func parseArgs(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		if args[0] == "--" {
+			return append(positional, args[1:]...)
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// loadList loads the list like the app does, reporting an include that
+// can't be read and going on with the other feeds
+func loadList() (*rss.List, error) {
+	l, _, err := loadListIncludes()
+	return l, err
 }
+
+// loadListIncludes is loadList, also telling whether an include couldn't be read
+func loadListIncludes() (l *rss.List, includeFailed bool, err error) {
+	l, err = tui.LoadList()
+	if errors.Is(err, rss.ErrInclude) {
+		fmt.Fprintln(os.Stderr, err)
+		return l, true, nil
+	}
+	if err != nil {
+		return l, false, &loadError{err}
+	}
+	return l, false, nil
+}
+
+// loadCachedList loads the list from the cache without using the network,
+// going on with the other feeds when an include isn't cached
+func loadCachedList() (*rss.List, error) {
+	l, err := tui.LoadCachedList()
+	if err != nil && !errors.Is(err, rss.ErrInclude) {
+		return l, &loadError{err}
+	}
+	return l, nil
+}
+
+// urlsPath is where urls.yaml lives. Lists from a backend have no urls.yaml
+// to edit.
+func urlsPath() (string, error) {
+	if os.Getenv("RSSBOAT_MINIFLUX_URL") != "" || os.Getenv("RSSBOAT_NEXTCLOUD_URL") != "" {
+		return "", errManagedByBackend
+	}
+
+	configFilePath, err := rss.ConfigFilePath()
+	if err != nil {
+		return "", &loadError{err}
+	}
+	return filepath.Join(configFilePath, "urls.yaml"), nil
+}
+
+var errManagedByBackend = errors.New("feeds are managed by the backend, add or remove them there")
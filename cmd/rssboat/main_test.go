@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/emilosman/rssboat/internal/rss"
+)
+
+// setupDirs points the config, cache and runtime dirs at temp dirs holding
+// urls.yaml, so commands neither touch the real list nor attach to a daemon
+func setupDirs(t *testing.T, urls string) string {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	t.Setenv("RSSBOAT_MINIFLUX_URL", "")
+	t.Setenv("RSSBOAT_NEXTCLOUD_URL", "")
+
+	path, err := urlsPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(urls), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCommands(t *testing.T) {
+	t.Run("Should parse flags before, between and after arguments", func(t *testing.T) {
+		tests := []struct {
+			args       []string
+			positional []string
+			flatten    bool
+			category   string
+		}{
+			{[]string{"feeds.opml"}, []string{"feeds.opml"}, false, ""},
+			{[]string{"--flatten", "feeds.opml"}, []string{"feeds.opml"}, true, ""},
+			{[]string{"feeds.opml", "--flatten"}, []string{"feeds.opml"}, true, ""},
+			{[]string{"a", "--category", "news", "b"}, []string{"a", "b"}, false, "news"},
+			{[]string{"a", "--", "--flatten"}, []string{"a", "--flatten"}, false, ""},
+			{nil, nil, false, ""},
+		}
+
+		for _, tt := range tests {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			flatten := fs.Bool("flatten", false, "")
+			category := fs.String("category", "", "")
+
+			positional := parseArgs(fs, tt.args)
+			if !slices.Equal(positional, tt.positional) || *flatten != tt.flatten || *category != tt.category {
+				t.Errorf("parseArgs(%q) = %q, flatten %v, category %q", tt.args, positional, *flatten, *category)
+			}
+		}
+	})
+
+	t.Run("Should exit 2 for config errors and 1 for the rest", func(t *testing.T) {
+		configErr := &rss.ConfigError{File: "urls.yaml", Line: 3, Err: rss.ErrUnknownKey}
+		includeErr := &rss.ConfigError{File: "urls.yaml", Line: 1, Err: fmt.Errorf("%w team.yaml: %w", rss.ErrInclude, os.ErrNotExist)}
+
+		tests := []struct {
+			err  error
+			want int
+		}{
+			{configErr, exitConfig},
+			{fmt.Errorf("loading: %w", configErr), exitConfig},
+			{includeErr, exitFailed},
+			{&loadError{fmt.Errorf("open urls.yaml: %w", os.ErrPermission)}, exitConfig},
+			{fmt.Errorf("%w: art", rss.ErrCategoryNotFound), exitConfig},
+			{errFeedsFailed, exitFailed},
+			{errors.New("network is down"), exitFailed},
+		}
+
+		for _, tt := range tests {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("Should add, import and export feeds in the config dir", func(t *testing.T) {
+		path := setupDirs(t, "news:\n  - https://example.com/news.xml\n")
+
+		if err := add([]string{"https://example.com/go.xml", "--category", "tech"}); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if err := add([]string{"--category", "tech", "https://example.com/go.xml"}); err == nil {
+			t.Error("Adding a feed twice should fail")
+		}
+
+		opmlPath := filepath.Join(t.TempDir(), "feeds.opml")
+		os.WriteFile(opmlPath, []byte(`<opml version="2.0"><body>
+<outline text="art"><outline text="design"><outline type="rss" xmlUrl="https://example.com/design.xml"/></outline></outline>
+</body></opml>`), 0644)
+		if err := importFeeds([]string{"opml", opmlPath, "--flatten"}); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		data, _ := os.ReadFile(path)
+		for _, want := range []string{"tech:\n  - https://example.com/go.xml", "art:\n  - https://example.com/design.xml"} {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("urls.yaml should contain %q, got:\n%s", want, data)
+			}
+		}
+
+		out := filepath.Join(t.TempDir(), "out.opml")
+		if err := exportFeeds([]string{"opml", "-o", out}); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		exported, _ := os.ReadFile(out)
+		for _, url := range []string{"news.xml", "go.xml", "design.xml"} {
+			if !strings.Contains(string(exported), "https://example.com/"+url) {
+				t.Errorf("Export should contain %s", url)
+			}
+		}
+
+		if err := remove([]string{"https://example.com/go.xml"}); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if data, _ := os.ReadFile(path); strings.Contains(string(data), "go.xml") {
+			t.Error("Removed feed should be gone from urls.yaml")
+		}
+	})
+
+	t.Run("Should import Newsboat feeds only into a urls.yaml that is used", func(t *testing.T) {
+		path := setupDirs(t, "news:\n  - https://example.com/news.xml\n")
+		newsboatUrls := filepath.Join(t.TempDir(), "urls")
+		os.WriteFile(newsboatUrls, []byte("https://example.com/go.xml tech\n"), 0644)
+
+		t.Setenv("RSSBOAT_MINIFLUX_URL", "https://miniflux.example.com")
+		err := importFeeds([]string{"newsboat", "--urls", newsboatUrls, "--cache", ""})
+		if !errors.Is(err, errManagedByBackend) {
+			t.Errorf("Expected errManagedByBackend, got %v", err)
+		}
+
+		t.Setenv("RSSBOAT_MINIFLUX_URL", "")
+		if err := importFeeds([]string{"newsboat", "--urls", newsboatUrls, "--cache", ""}); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if data, _ := os.ReadFile(path); !strings.Contains(string(data), "https://example.com/go.xml") {
+			t.Errorf("urls.yaml should contain the Newsboat feed, got:\n%s", data)
+		}
+	})
+
+	t.Run("Should report problems in urls.yaml as config errors", func(t *testing.T) {
+		setupDirs(t, "news:\n  - url: https://example.com/news.xml\n    colour: red\n")
+
+		_, err := loadList()
+		if exitCode(err) != exitConfig {
+			t.Errorf("Expected exit code %d, got %d for %v", exitConfig, exitCode(err), err)
+		}
+		if err := check(nil); err == nil {
+			t.Error("check should fail")
+		}
+	})
+
+	t.Run("Should exit 2 before refreshing when the config is wrong", func(t *testing.T) {
+		setupDirs(t, "news:\n  - https://example.com/news.xml\n")
+
+		err := refresh([]string{"--quiet", "art"})
+		if !errors.Is(err, rss.ErrCategoryNotFound) || exitCode(err) != exitConfig {
+			t.Errorf("Unknown category should exit %d, got %v", exitConfig, err)
+		}
+
+		configFilePath, _ := rss.ConfigFilePath()
+		os.WriteFile(filepath.Join(configFilePath, "config.yaml"), []byte("display:\n  wrap_width: -1\n"), 0644)
+		err = refresh([]string{"--quiet"})
+		if exitCode(err) != exitConfig {
+			t.Errorf("Invalid config.yaml should exit %d, got %v", exitConfig, err)
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/emilosman/rssboat/internal/config"
+	"github.com/emilosman/rssboat/internal/rss"
+)
+
+var errFeedsFailed = errors.New("some feeds failed to refresh")
+
+type refreshReport struct {
+	Feeds  []feedReport `json:"feeds"`
+	New    int          `json:"new"`
+	Failed int          `json:"failed"`
+}
+
+type feedReport struct {
+	Url   string `json:"url"`
+	Title string `json:"title"`
+	New   int    `json:"new"`
+	Error string `json:"error,omitempty"`
+}
+
+// refresh fetches feeds that are due, every feed with --all, or the feeds of
+// the given categories, and saves them to the cache for the app to open
+// with. An app that is open merges the new items within a few seconds.
+func refresh(args []string) error {
+	fs := flag.NewFlagSet("refresh", flag.ExitOnError)
+	all := fs.Bool("all", false, "refresh every feed, even those refreshed recently")
+	quiet := fs.Bool("quiet", false, "only print errors")
+	asJson := fs.Bool("json", false, "print the summary as JSON")
+	categories := parseArgs(fs, args)
+
+	// A broken config.yaml would stop the app, so report it here too
+	if _, err := config.LoadUserConfig(); err != nil {
+		return &loadError{err}
+	}
+
+	// The other feeds are still refreshed when an include fails
+	l, includeErr, err := loadListIncludes()
+	if err != nil {
+		return err
+	}
+
+	var results <-chan rss.FeedResult
+	switch {
+	case len(categories) > 0:
+		var feeds []*rss.RssFeed
+		feeds, err = categoryFeeds(l, categories)
+		if err == nil {
+			results, err = rss.UpdateFeeds(feeds...)
+		}
+	case *all:
+		results, err = rss.UpdateFeeds(subscribed(l)...)
+	default:
+		results, err = l.UpdateAllFeeds()
+	}
+	if err != nil {
+		return err
+	}
+
+	var report refreshReport
+	for res := range results {
+		feed := feedReport{Url: res.Feed.Url, Title: res.Feed.Name(), New: res.New}
+		if res.Err != nil {
+			feed.Error = res.Err.Error()
+			report.Failed++
+		}
+		report.New += feed.New
+		report.Feeds = append(report.Feeds, feed)
+
+		switch {
+		case *asJson:
+		case res.Err != nil:
+			fmt.Fprintf(os.Stderr, "%s: %v\n", feed.Url, res.Err)
+		case !*quiet:
+			fmt.Printf("%s: %d new\n", feed.Title, feed.New)
+		}
+	}
+
+	l.RefreshBookmarks()
+	if err := l.SaveCache(); err != nil {
+		return err
+	}
+
+	if *asJson {
+		slices.SortStableFunc(report.Feeds, func(a, b feedReport) int {
+			return slices.IndexFunc(l.Feeds, func(f *rss.RssFeed) bool { return f.Url == a.Url }) -
+				slices.IndexFunc(l.Feeds, func(f *rss.RssFeed) bool { return f.Url == b.Url })
+		})
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else if !*quiet {
+		fmt.Printf("Refreshed %d feeds, %d new items\n", len(report.Feeds), report.New)
+	}
+
+	if report.Failed > 0 {
+		return fmt.Errorf("%w: %d of %d", errFeedsFailed, report.Failed, len(report.Feeds))
+	}
+	if includeErr {
+		return errFeedsFailed
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/emilosman/rssboat/internal/rss"
+)
+
+// search prints the cached items matching a query, best matches first. Like
+// items it doesn't use the network.
+func search(args []string) error {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	category := fs.String("category", "", "only items of this category and its nested categories")
+	feedUrl := fs.String("feed", "", "only items of this feed")
+	unread := fs.Bool("unread", false, "only unread items")
+	bookmarked := fs.Bool("bookmarked", false, "only bookmarked items")
+	tag := fs.String("tag", "", "only items of feeds with this tag")
+	since := fs.String("since", "", "only items published or fetched within this long, e.g. 24h or 7d")
+	limit := fs.Int("limit", 20, "print at most this many results, 0 for all")
+	format := fs.String("format", "text", "text or jsonl")
+	words := parseArgs(fs, args)
+	if len(words) == 0 {
+		return fmt.Errorf("usage: rssboat search <query> [--category c] [--feed url] [--unread] [--bookmarked] [--tag t]")
+	}
+	if *format != "text" && *format != "jsonl" {
+		return fmt.Errorf("unknown format %q, use text or jsonl", *format)
+	}
+
+	q := rss.ItemQuery{Category: *category, Feed: *feedUrl, Unread: *unread, Bookmarked: *bookmarked, Tag: *tag}
+	if *since != "" {
+		d, err := rss.ParseDuration(*since)
+		if err != nil {
+			return err
+		}
+		q.Since = time.Now().Add(-d)
+	}
+
+	l, err := loadCachedList()
+	if err != nil {
+		return err
+	}
+	results, err := l.Search(strings.Join(words, " "), q)
+	if err != nil {
+		return err
+	}
+	if *limit > 0 && len(results) > *limit {
+		results = results[:*limit]
+	}
+
+	if *format == "jsonl" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		for _, r := range results {
+			if err := enc.Encode(r.Record(false)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for i, r := range results {
+		if i > 0 {
+			fmt.Println()
+		}
+		details := []string{r.Feed.Name()}
+		if date := r.Item.Date(); date != nil {
+			details = append(details, date.Local().Format(rss.DefaultDateFormat))
+		}
+		fmt.Println(oneLine(r.Item.Title()))
+		fmt.Println("  " + oneLine(strings.Join(details, ", ")))
+		if link := r.Item.Link(); link != "" {
+			fmt.Println("  " + link)
+		}
+	}
+	return nil
+}
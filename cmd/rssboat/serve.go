@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/emilosman/rssboat/internal/fever"
+	"github.com/emilosman/rssboat/internal/rss"
+)
+
+func serve(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	useFever := fs.Bool("fever", false, "serve the Fever API")
+	addr := fs.String("addr", "127.0.0.1:8880", "listen address")
+	email := fs.String("email", os.Getenv("RSSBOAT_FEVER_EMAIL"), "Fever login email")
+	password := fs.String("password", os.Getenv("RSSBOAT_FEVER_PASSWORD"), "Fever login password")
+	refresh := fs.Duration("refresh", 30*time.Minute, "feed refresh interval, 0 to disable")
+	parseArgs(fs, args)
+
+	if !*useFever {
+		return fmt.Errorf("no API selected, use --fever")
+	}
+	if *email == "" || *password == "" {
+		return fmt.Errorf("--email and --password are required")
+	}
+
+	if _, err := urlsPath(); err != nil {
+		return errors.New("rssboat serve serves the feeds of urls.yaml, unset RSSBOAT_MINIFLUX_URL and RSSBOAT_NEXTCLOUD_URL")
+	}
+
+	configFilePath, err := rss.ConfigFilePath()
+	if err != nil {
+		return &loadError{err}
+	}
+	// Like the daemon, feeds of an include that can't be read are left out
+	l, err := rss.LoadList(os.DirFS(configFilePath))
+	if errors.Is(err, rss.ErrInclude) {
+		log.Println(err)
+	} else if err != nil {
+		return &loadError{err}
+	}
+
+	cacheDirPath, err := rss.CacheDirPath()
+	if err != nil {
+		return err
+	}
+
+	s, err := fever.NewServer(l, fever.ApiKey(*email, *password), filepath.Join(cacheDirPath, "fever.json"))
+	if err != nil {
+		return err
+	}
+	s.Save = l.SaveCache
+
+	if *refresh > 0 {
+		go func() {
+			for {
+				if err := s.Refresh(); err != nil {
+					log.Println("Error refreshing feeds:", err)
+				}
+				time.Sleep(*refresh)
+			}
+		}()
+	}
+
+	log.Printf("Serving Fever API on http://%s/?api", *addr)
+	return http.ListenAndServe(*addr, s)
+}
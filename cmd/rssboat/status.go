@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/template"
+)
+
+type unreadStatus struct {
+	Unread     int              `json:"unread"`
+	Categories []categoryStatus `json:"categories"`
+}
+
+type categoryStatus struct {
+	Name   string `json:"name"`
+	Unread int    `json:"unread"`
+}
+
+// status prints unread counts from the cache, without using the network, for
+// status bars polling every few seconds
+func status(args []string) error {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	format := fs.String("format", "text", `text, json or a Go template such as "{{.Unread}} unread"`)
+	parseArgs(fs, args)
+
+	l, err := loadCachedList()
+	if err != nil {
+		return err
+	}
+
+	var s unreadStatus
+	for _, f := range subscribed(l) {
+		s.Unread += f.UnreadCount()
+	}
+	for _, c := range l.Categories() {
+		feeds, _ := l.GetCategory(c)
+		unread := 0
+		for _, f := range feeds {
+			unread += f.UnreadCount()
+		}
+		s.Categories = append(s.Categories, categoryStatus{Name: c, Unread: unread})
+	}
+
+	switch *format {
+	case "text":
+		for _, c := range s.Categories {
+			fmt.Printf("%s\t%d\n", c.Name, c.Unread)
+		}
+		fmt.Printf("Total\t%d\n", s.Unread)
+		return nil
+	case "json":
+		return json.NewEncoder(os.Stdout).Encode(s)
+	}
+
+	tmpl, err := template.New("status").Parse(*format)
+	if err != nil {
+		return err
+	}
+	if err := tmpl.Execute(os.Stdout, s); err != nil {
+		return err
+	}
+	fmt.Println()
+	return nil
+}
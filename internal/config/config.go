@@ -0,0 +1,358 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	yaml "github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+
+	"github.com/emilosman/rssboat/internal/rss"
+)
+
+const FileName = "config.yaml"
+
+// Config holds the app settings from config.yaml. Keys left out keep their defaults.
+type Config struct {
+	// Browser opens links, {url} is replaced with the link or appended when missing.
+	// Empty uses the system default.
+	Browser string `yaml:"browser"`
+	// Editor edits urls.yaml. Empty uses $EDITOR, then nvim or notepad.
+	Editor   string   `yaml:"editor"`
+	Refresh  Refresh  `yaml:"refresh"`
+	Display  Display  `yaml:"display"`
+	MarkRead MarkRead `yaml:"mark_read"`
+	// Keys binds actions to keys per view: feeds, items and article
+	Keys   map[string]map[string]KeyList `yaml:"keys"`
+	Theme  Theme                         `yaml:"theme"`
+	Digest Digest                        `yaml:"digest"`
+}
+
+type Theme struct {
+	// Preset is one of Presets
+	Preset string `yaml:"preset"`
+	Colors Colors `yaml:"colors"`
+}
+
+// Presets are the built-in themes
+var Presets = []string{"dark", "light", "high-contrast"}
+
+// Colors override the preset's colour of each style, empty keeps the preset
+type Colors struct {
+	Unread      Color `yaml:"unread"`
+	Bookmark    Color `yaml:"bookmark"`
+	Error       Color `yaml:"error"`
+	Title       Color `yaml:"title"`
+	Status      Color `yaml:"status"`
+	ActiveTab   Color `yaml:"active_tab"`
+	UnreadTab   Color `yaml:"unread_tab"`
+	InactiveTab Color `yaml:"inactive_tab"`
+}
+
+// Color is a hex colour such as "#00cf42", or an ANSI colour number from 0 to 255
+type Color string
+
+func (c *Color) UnmarshalYAML(node ast.Node) error {
+	var raw string
+	if err := yaml.NodeToValue(node, &raw); err != nil {
+		return &valueError{node: node, err: err}
+	}
+
+	if !validColor(raw) {
+		return &valueError{node: node, err: fmt.Errorf("%w: %q", ErrInvalidColor, raw)}
+	}
+
+	*c = Color(raw)
+	return nil
+}
+
+func validColor(s string) bool {
+	if hex, ok := strings.CutPrefix(s, "#"); ok {
+		switch len(hex) {
+		case 3, 6, 8:
+			_, err := strconv.ParseUint(hex, 16, 32)
+			return err == nil
+		}
+		return false
+	}
+
+	n, err := strconv.Atoi(s)
+	return err == nil && n >= 0 && n <= 255
+}
+
+type Refresh struct {
+	// OnStartup refreshes all feeds when the app opens
+	OnStartup bool `yaml:"on_startup"`
+	// Interval refreshes all feeds while the app is open, 0 disables
+	Interval Duration `yaml:"interval"`
+}
+
+type Display struct {
+	// WrapWidth is the column articles wrap at, 0 wraps at the window width
+	WrapWidth int `yaml:"wrap_width"`
+	// StatusTimeout clears status messages after a while, 0 keeps them
+	StatusTimeout Duration `yaml:"status_timeout"`
+	// DateFormat is a Go time layout
+	DateFormat string `yaml:"date_format"`
+}
+
+type MarkRead struct {
+	// OnView marks items read when viewed in the app
+	OnView bool `yaml:"on_view"`
+	// OnOpen marks items read when opened in the browser
+	OnOpen bool `yaml:"on_open"`
+}
+
+// Digest is where rssboat digest sends its email
+type Digest struct {
+	// From defaults to the first address of To
+	From string   `yaml:"from"`
+	To   []string `yaml:"to"`
+	SMTP SMTP     `yaml:"smtp"`
+}
+
+type SMTP struct {
+	Host string `yaml:"host"`
+	// Port 465 uses TLS from the start, others STARTTLS when the server offers it
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	// Password can be left out and set in RSSBOAT_SMTP_PASSWORD instead
+	Password string `yaml:"password"`
+}
+
+// KeyList is one key or a list of keys, an empty list unbinds the action
+type KeyList []string
+
+func (k *KeyList) UnmarshalYAML(node ast.Node) error {
+	var keys []string
+	if _, ok := node.(*ast.SequenceNode); ok {
+		if err := yaml.NodeToValue(node, &keys); err != nil {
+			return &valueError{node: node, err: err}
+		}
+	} else {
+		var single string
+		if err := yaml.NodeToValue(node, &single); err != nil {
+			return &valueError{node: node, err: err}
+		}
+		keys = []string{single}
+	}
+
+	*k = keys
+	return nil
+}
+
+// Duration accepts the same values as urls.yaml, such as 30m, 12h or 7d
+type Duration time.Duration
+
+func (d *Duration) UnmarshalYAML(node ast.Node) error {
+	var raw string
+	if err := yaml.NodeToValue(node, &raw); err != nil {
+		return &valueError{node: node, err: err}
+	}
+
+	v, err := rss.ParseDuration(raw)
+	if err != nil {
+		return &valueError{node: node, err: err}
+	}
+
+	*d = Duration(v)
+	return nil
+}
+
+// valueError keeps the position of an invalid value until the file name is known
+type valueError struct {
+	node ast.Node
+	err  error
+}
+
+func (e *valueError) Error() string {
+	return e.err.Error()
+}
+
+func Default() *Config {
+	return &Config{
+		Display: Display{
+			WrapWidth:     80,
+			StatusTimeout: Duration(8 * time.Second),
+			DateFormat:    rss.DefaultDateFormat,
+		},
+		MarkRead: MarkRead{
+			OnView: true,
+			OnOpen: true,
+		},
+		Theme: Theme{
+			Preset: "dark",
+		},
+		Digest: Digest{
+			SMTP: SMTP{Port: 587},
+		},
+	}
+}
+
+// Load reads config.yaml from the filesystem. A missing file gives the defaults,
+// and the defaults are also returned alongside any error.
+func Load(filesystem fs.FS, filename string) (*Config, error) {
+	c := Default()
+
+	file, err := filesystem.Open(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return c, err
+	}
+
+	parsed, err := parser.ParseBytes(data, parser.ParseComments)
+	if err != nil {
+		return c, configError(filename, err)
+	}
+	if isEmpty(parsed) {
+		// Decoding a file of only comments would zero the defaults
+		return c, nil
+	}
+
+	loaded := Default()
+	if err := yaml.UnmarshalWithOptions(data, loaded, yaml.Strict()); err != nil {
+		return c, configError(filename, err)
+	}
+	if err := loaded.validate(); err != nil {
+		return c, &rss.ConfigError{File: filename, Err: err}
+	}
+
+	return loaded, nil
+}
+
+// LoadUserConfig loads config.yaml from the config dir, creating a documented
+// one on first run
+func LoadUserConfig() (*Config, error) {
+	dir, err := rss.ConfigFilePath()
+	if err != nil {
+		return Default(), err
+	}
+
+	path := filepath.Join(dir, FileName)
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		if err := os.WriteFile(path, []byte(ExampleFile), 0644); err != nil {
+			return Default(), err
+		}
+	}
+
+	return Load(os.DirFS(dir), FileName)
+}
+
+func (c *Config) validate() error {
+	switch {
+	case c.Display.WrapWidth < 0:
+		return ErrInvalidWrapWidth
+	case c.Display.DateFormat == "":
+		return ErrInvalidDateFormat
+	case !slices.Contains(Presets, c.Theme.Preset):
+		return fmt.Errorf("%w: %q, use one of %s", ErrInvalidPreset, c.Theme.Preset, strings.Join(Presets, ", "))
+	case c.Digest.SMTP.Port < 1 || c.Digest.SMTP.Port > 65535:
+		return ErrInvalidSmtpPort
+	}
+	return nil
+}
+
+// BrowserCommand returns the command opening url, or nil for the system default
+func (c *Config) BrowserCommand(url string) []string {
+	return command(c.Browser, "{url}", url)
+}
+
+// Editors that open a file at a line given as +line before it
+var lineEditors = []string{"vi", "vim", "nvim", "nvi", "nano", "emacs", "emacsclient", "micro", "kak", "joe", "mg"}
+
+// EditorCommand returns the command editing file at line, or at the top when
+// line is 0. {line} in the editor setting is replaced with the line, editors
+// known to take +line get it before the file.
+func (c *Config) EditorCommand(file string, line int) []string {
+	editor := strings.TrimSpace(c.Editor)
+	if editor == "" {
+		editor = strings.TrimSpace(os.Getenv("EDITOR"))
+	}
+	if editor == "" {
+		editor = "nvim"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	if strings.Contains(editor, "{line}") {
+		editor = strings.ReplaceAll(editor, "{line}", strconv.Itoa(max(line, 1)))
+	} else if line > 0 && !strings.Contains(editor, "{file}") {
+		name := strings.TrimSuffix(filepath.Base(strings.Fields(editor)[0]), ".exe")
+		if slices.Contains(lineEditors, name) {
+			editor += " +" + strconv.Itoa(line)
+		}
+	}
+
+	return command(editor, "{file}", file)
+}
+
+func command(line, placeholder, arg string) []string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	replaced := false
+	for i, field := range fields {
+		if strings.Contains(field, placeholder) {
+			fields[i] = strings.ReplaceAll(field, placeholder, arg)
+			replaced = true
+		}
+	}
+	if !replaced {
+		fields = append(fields, arg)
+	}
+	return fields
+}
+
+func isEmpty(file *ast.File) bool {
+	for _, doc := range file.Docs {
+		switch doc.Body.(type) {
+		case nil, *ast.NullNode, *ast.CommentGroupNode:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func configError(filename string, err error) error {
+	var valueErr *valueError
+	if errors.As(err, &valueErr) {
+		return &rss.ConfigError{
+			File: filename,
+			Line: valueErr.node.GetToken().Position.Line,
+			Err:  valueErr.err,
+		}
+	}
+
+	var yamlErr yaml.Error
+	if errors.As(err, &yamlErr) && yamlErr.GetToken() != nil {
+		return &rss.ConfigError{
+			File: filename,
+			Line: yamlErr.GetToken().Position.Line,
+			Err:  errors.New(yamlErr.GetMessage()),
+		}
+	}
+
+	return fmt.Errorf("%s: %w", filename, err)
+}
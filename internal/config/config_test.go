@@ -0,0 +1,197 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/emilosman/rssboat/internal/rss"
+)
+
+func load(t *testing.T, data string) (*Config, error) {
+	t.Helper()
+	fs := fstest.MapFS{FileName: {Data: []byte(data)}}
+	return Load(fs, FileName)
+}
+
+func TestConfig(t *testing.T) {
+	t.Run("Should use defaults when file is missing", func(t *testing.T) {
+		c, err := Load(fstest.MapFS{}, FileName)
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if !reflect.DeepEqual(c, Default()) {
+			t.Errorf("Wrong config, got %+v", c)
+		}
+	})
+
+	t.Run("Should keep defaults for the commented example", func(t *testing.T) {
+		c, err := load(t, ExampleFile)
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if !reflect.DeepEqual(c, Default()) {
+			t.Errorf("Wrong config, got %+v", c)
+		}
+	})
+
+	t.Run("Should accept every setting in the example", func(t *testing.T) {
+		var lines []string
+		for _, line := range strings.Split(ExampleFile, "\n") {
+			if strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "# ") {
+				line = line[1:]
+			}
+			lines = append(lines, line)
+		}
+
+		c, err := load(t, strings.Join(lines, "\n"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if c.Browser != "firefox --new-tab {url}" || c.Editor != "vim" {
+			t.Errorf("Wrong commands, got %+v", c)
+		}
+	})
+
+	t.Run("Should override defaults with set keys", func(t *testing.T) {
+		c, err := load(t, "refresh:\n  interval: 1d\ndisplay:\n  wrap_width: 0\nmark_read:\n  on_open: false\n")
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if time.Duration(c.Refresh.Interval) != 24*time.Hour {
+			t.Errorf("Wrong interval, got %v", c.Refresh.Interval)
+		}
+		if c.Display.WrapWidth != 0 || c.MarkRead.OnOpen {
+			t.Errorf("Set keys not applied, got %+v", c)
+		}
+		if c.Display.DateFormat != rss.DefaultDateFormat || !c.MarkRead.OnView {
+			t.Errorf("Missing keys should keep defaults, got %+v", c)
+		}
+
+		c, err = load(t, "digest:\n  to: [me@example.com]\n  smtp:\n    host: smtp.example.com\n")
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if c.Digest.SMTP.Host != "smtp.example.com" || c.Digest.SMTP.Port != 587 || !slices.Equal(c.Digest.To, []string{"me@example.com"}) {
+			t.Errorf("Wrong digest settings, got %+v", c.Digest)
+		}
+	})
+
+	t.Run("Should report unknown keys with their line", func(t *testing.T) {
+		_, err := load(t, "display:\n  wrap_width: 100\n  wrap: 80\n")
+
+		var configErr *rss.ConfigError
+		if !errors.As(err, &configErr) || configErr.Line != 3 {
+			t.Errorf("Expected error on line 3, got %v", err)
+		}
+	})
+
+	t.Run("Should report invalid values with their line", func(t *testing.T) {
+		c, err := load(t, "refresh:\n  on_startup: true\n  interval: soon\n")
+
+		var configErr *rss.ConfigError
+		if !errors.As(err, &configErr) || configErr.Line != 3 {
+			t.Errorf("Expected error on line 3, got %v", err)
+		}
+		if !errors.Is(err, rss.ErrInvalidDuration) {
+			t.Errorf("Expected ErrInvalidDuration, got %v", err)
+		}
+		if !reflect.DeepEqual(c, Default()) {
+			t.Error("Defaults should be returned with an error")
+		}
+	})
+
+	t.Run("Should read single keys and lists of keys", func(t *testing.T) {
+		c, err := load(t, "keys:\n  feeds:\n    refresh_all: [R, f5]\n    quit: x\n    edit: []\n")
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		feeds := c.Keys["feeds"]
+		if !slices.Equal(feeds["refresh_all"], KeyList{"R", "f5"}) || !slices.Equal(feeds["quit"], KeyList{"x"}) {
+			t.Errorf("Wrong keys, got %v", feeds)
+		}
+		if keys, ok := feeds["edit"]; !ok || len(keys) != 0 {
+			t.Error("Empty list should unbind the action")
+		}
+	})
+
+	t.Run("Should check theme colours and presets", func(t *testing.T) {
+		c, err := load(t, "theme:\n  preset: light\n  colors:\n    unread: \"#00ff00\"\n    title: 205\n")
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if c.Theme.Preset != "light" || c.Theme.Colors.Unread != "#00ff00" || c.Theme.Colors.Title != "205" {
+			t.Errorf("Wrong theme, got %+v", c.Theme)
+		}
+
+		_, err = load(t, "theme:\n  colors:\n    unread: green\n")
+		var configErr *rss.ConfigError
+		if !errors.As(err, &configErr) || configErr.Line != 3 || !errors.Is(err, ErrInvalidColor) {
+			t.Errorf("Expected invalid colour on line 3, got %v", err)
+		}
+
+		_, err = load(t, "theme:\n  preset: solarized\n")
+		if !errors.Is(err, ErrInvalidPreset) {
+			t.Errorf("Expected ErrInvalidPreset, got %v", err)
+		}
+	})
+
+	t.Run("Should validate settings", func(t *testing.T) {
+		_, err := load(t, "display:\n  wrap_width: -1\n")
+		if !errors.Is(err, ErrInvalidWrapWidth) {
+			t.Errorf("Expected ErrInvalidWrapWidth, got %v", err)
+		}
+
+		_, err = load(t, "digest:\n  smtp:\n    port: 0\n")
+		if !errors.Is(err, ErrInvalidSmtpPort) {
+			t.Errorf("Expected ErrInvalidSmtpPort, got %v", err)
+		}
+	})
+
+	t.Run("Should build commands", func(t *testing.T) {
+		c := Default()
+		if c.BrowserCommand("https://example.com") != nil {
+			t.Error("Empty browser should use the system default")
+		}
+
+		c.Browser = "firefox --new-tab {url}"
+		want := []string{"firefox", "--new-tab", "https://example.com"}
+		if got := c.BrowserCommand("https://example.com"); !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+
+		t.Setenv("EDITOR", "nano")
+		want = []string{"nano", "urls.yaml"}
+		if got := c.EditorCommand("urls.yaml", 0); !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+
+		want = []string{"nano", "+12", "urls.yaml"}
+		if got := c.EditorCommand("urls.yaml", 12); !slices.Equal(got, want) {
+			t.Errorf("Should open at the line, got %v, want %v", got, want)
+		}
+
+		c.Editor = "code --goto {file}:{line}"
+		want = []string{"code", "--goto", "urls.yaml:12"}
+		if got := c.EditorCommand("urls.yaml", 12); !slices.Equal(got, want) {
+			t.Errorf("Should replace {line}, got %v, want %v", got, want)
+		}
+
+		c.Editor = "notepad"
+		want = []string{"notepad", "urls.yaml"}
+		if got := c.EditorCommand("urls.yaml", 12); !slices.Equal(got, want) {
+			t.Errorf("Unknown editors should not get +line, got %v, want %v", got, want)
+		}
+
+		c.Editor = "  "
+		t.Setenv("EDITOR", " ")
+		if got := c.EditorCommand("urls.yaml", 12); len(got) == 0 || got[len(got)-1] != "urls.yaml" {
+			t.Errorf("Blank editor should fall back to the default, got %v", got)
+		}
+	})
+}
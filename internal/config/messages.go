@@ -0,0 +1,81 @@
+package config
+
+import "errors"
+
+var (
+	ErrInvalidWrapWidth  = errors.New("display.wrap_width can't be negative")
+	ErrInvalidDateFormat = errors.New("display.date_format can't be empty")
+	ErrInvalidPreset     = errors.New("Unknown theme.preset")
+	ErrInvalidColor      = errors.New("Invalid colour, use a hex colour like #00cf42 or a number from 0 to 255")
+	ErrInvalidSmtpPort   = errors.New("digest.smtp.port must be from 1 to 65535")
+)
+
+const ExampleFile = `# rssboat settings, written in YAML format.
+# Every setting is optional, uncomment a line to change its default.
+# The list of feeds lives in urls.yaml next to this file.
+
+# Command opening links, {url} is replaced with the link. Empty uses the system default.
+#browser: firefox --new-tab {url}
+
+# Command editing urls.yaml with shift+e. Empty uses $EDITOR, then nvim (notepad on Windows).
+# {line} is replaced with the line to open at, vi, nano, emacs and similar get +line.
+#editor: vim
+
+#refresh:
+#  # Refresh all feeds when the app opens
+#  on_startup: false
+#  # Refresh all feeds periodically while the app is open, e.g. 30m or 2h. 0 disables.
+#  interval: 0
+
+#display:
+#  # Column articles are wrapped at, 0 wraps at the window width
+#  wrap_width: 80
+#  # How long status messages stay, 0 keeps them until the next one
+#  status_timeout: 8s
+#  # Date layout in Go format, see https://pkg.go.dev/time#pkg-constants
+#  date_format: "2006-01-02 15:04"
+
+#mark_read:
+#  # Mark items read when viewing them in the app
+#  on_view: true
+#  # Mark items read when opening them in the browser
+#  on_open: true
+
+# Keys per view, replacing the default keys of an action. [] unbinds an action.
+# Press ? in the app to see every action, they're listed in the README.
+#keys:
+#  feeds:
+#    refresh_all: [R, F5]
+#    mark_all_read: C
+#  items:
+#    toggle_read: [a, space]
+#  article:
+#    next: [right, l, j]
+
+#theme:
+#  # dark, light or high-contrast. Colours are turned off when NO_COLOR is set.
+#  preset: dark
+#  # Override single colours with a hex colour or a terminal colour number (0-255)
+#  colors:
+#    unread: "#00cf42"
+#    bookmark: "#ffff00"
+#    error: "#e53636"
+#    title: "#ff4fff"
+#    status: "#767676"
+#    active_tab: "#ff4fff"
+#    unread_tab: "#00cf42"
+#    inactive_tab: "#dfdfdf"
+
+# Where rssboat digest sends its email
+#digest:
+#  # Defaults to the first address of to
+#  from: rssboat@example.com
+#  to: [me@example.com]
+#  smtp:
+#    host: smtp.example.com
+#    # 465 uses TLS from the start, others STARTTLS when the server offers it
+#    port: 587
+#    username: me@example.com
+#    # Or set RSSBOAT_SMTP_PASSWORD instead
+#    password: secret
+`
@@ -0,0 +1,276 @@
+package daemon
+
+import (
+	"errors"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"time"
+
+	"github.com/emilosman/rssboat/internal/rss"
+	"github.com/mmcdole/gofeed"
+)
+
+// Client talks to a running rssboat daemon. It is an rss.Backend, so the app
+// and commands use the daemon's list like a Miniflux one.
+type Client struct {
+	rpc *rpc.Client
+	// seq of the last event pulled, see Poll
+	seq int64
+}
+
+// Dial connects to the daemon listening at path
+func Dial(path string) (*Client, error) {
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{rpc: jsonrpc.NewClient(conn)}, nil
+}
+
+// Call calls a Service method by name, such as "Items"
+func (c *Client) Call(method string, args, reply any) error {
+	return c.rpc.Call("Rssboat."+method, args, reply)
+}
+
+func (c *Client) Close() error {
+	return c.rpc.Close()
+}
+
+func (c *Client) Name() string {
+	return "daemon"
+}
+
+// Load adds the daemon's feeds to the list, see Pull for their items
+func (c *Client) Load(l *rss.List) error {
+	var feeds []Feed
+	if err := c.Call("Feeds", FeedsArgs{}, &feeds); err != nil {
+		return err
+	}
+
+	for _, f := range feeds {
+		for _, category := range f.Categories {
+			l.AddFeeds(f.toFeed(category))
+		}
+	}
+	return nil
+}
+
+// Pull merges the items of every feed, after Load and the list's cache have
+// been restored
+func (c *Client) Pull(l *rss.List) error {
+	// Changes made while pulling come with the next Poll
+	var events []Event
+	if err := c.Call("Events", EventsArgs{After: c.seq, Wait: "0s"}, &events); err != nil {
+		return err
+	}
+	if len(events) > 0 {
+		c.seq = events[len(events)-1].Seq
+	}
+
+	var records []rss.ItemRecord
+	if err := c.Call("Items", ItemsArgs{Content: true}, &records); err != nil {
+		return err
+	}
+
+	items := map[string][]*rss.RssItem{}
+	for _, r := range records {
+		items[r.FeedUrl] = append(items[r.FeedUrl], toItem(r))
+	}
+	for url, feedItems := range items {
+		if f, ok := l.FeedIndex[url]; ok {
+			f.MergeRemote(feedItems)
+		}
+	}
+
+	l.RefreshBookmarks()
+	return nil
+}
+
+// Push sends the read and bookmark changes of the feed's items
+func (c *Client) Push(f *rss.RssFeed) error {
+	var done bool
+	for _, item := range f.Pending() {
+		args := ItemArgs{Feed: f.Url, Key: item.Key(), Read: item.Read, Bookmark: item.Bookmark}
+		if item.Read != item.Synced.Read {
+			if err := c.Call("SetRead", args, &done); err != nil {
+				return err
+			}
+		}
+		if item.Bookmark != item.Synced.Bookmark {
+			if err := c.Call("SetBookmark", args, &done); err != nil {
+				return err
+			}
+		}
+		item.MarkSynced()
+	}
+	return nil
+}
+
+// Sync pushes changes, has the daemon refresh the feed and pulls its items
+func (c *Client) Sync(f *rss.RssFeed) error {
+	if err := c.Push(f); err != nil {
+		return err
+	}
+
+	var refreshed RefreshReply
+	if err := c.Call("Refresh", RefreshArgs{Feeds: []string{f.Url}}, &refreshed); err != nil {
+		return err
+	}
+	if msg, ok := refreshed.Errors[f.Url]; ok {
+		return errors.New(msg)
+	}
+
+	feed, items, err := c.pullFeed(f.Url)
+	if err != nil {
+		return err
+	}
+	if feed != nil {
+		f.Feed = feed.toFeed("").Feed
+	}
+	f.MergeRemote(items)
+	return nil
+}
+
+// pullFeed gets a feed and its items, the feed is nil when it isn't fetched
+func (c *Client) pullFeed(url string) (*Feed, []*rss.RssItem, error) {
+	var feeds []Feed
+	if err := c.Call("Feeds", FeedsArgs{Feed: url}, &feeds); err != nil {
+		return nil, nil, err
+	}
+	var records []rss.ItemRecord
+	if err := c.Call("Items", ItemsArgs{Feed: url, Content: true}, &records); err != nil {
+		return nil, nil, err
+	}
+
+	items := make([]*rss.RssItem, 0, len(records))
+	for _, r := range records {
+		items = append(items, toItem(r))
+	}
+	if len(feeds) == 1 {
+		return &feeds[0], items, nil
+	}
+	return nil, items, nil
+}
+
+// Changes are what the daemon changed since the last Poll, for another client
+// or on its own
+type Changes struct {
+	Events []Event
+	// FeedsChanged is set when the daemon applied changes to urls.yaml
+	FeedsChanged bool
+	// Refreshed feeds and their items, by URL
+	feeds map[string]*Feed
+	items map[string][]*rss.RssItem
+}
+
+// Poll waits up to wait for events after the last Pull or Poll, and gets the
+// items of the feeds they refreshed
+func (c *Client) Poll(wait time.Duration) (*Changes, error) {
+	var events []Event
+	if err := c.Call("Events", EventsArgs{After: c.seq, Wait: wait.String()}, &events); err != nil {
+		return nil, err
+	}
+
+	changes := &Changes{Events: events, feeds: map[string]*Feed{}, items: map[string][]*rss.RssItem{}}
+	for _, ev := range events {
+		c.seq = ev.Seq
+		switch ev.Type {
+		case EventFeeds:
+			changes.FeedsChanged = true
+		case EventRefreshed:
+			if _, ok := changes.items[ev.Feed]; ok || ev.Error != "" {
+				continue
+			}
+			feed, items, err := c.pullFeed(ev.Feed)
+			var serverErr rpc.ServerError
+			if errors.As(err, &serverErr) {
+				// Removed from urls.yaml since
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			changes.feeds[ev.Feed], changes.items[ev.Feed] = feed, items
+		}
+	}
+	return changes, nil
+}
+
+// Apply applies the changes to the list and returns how many items are new.
+// Local changes not pushed yet are kept, as in MergeRemote.
+func (ch *Changes) Apply(l *rss.List) int {
+	added := 0
+	for _, ev := range ch.Events {
+		f, ok := l.FeedIndex[ev.Feed]
+		if !ok || f == l.Bookmarks() {
+			continue
+		}
+
+		switch ev.Type {
+		case EventRefreshed:
+			f.Error = ev.Error
+			items, ok := ch.items[ev.Feed]
+			if !ok {
+				continue
+			}
+			if feed := ch.feeds[ev.Feed]; feed != nil {
+				f.Feed = feed.toFeed("").Feed
+			}
+			f.MergeRemote(items)
+			added += ev.New
+			// Later events for the feed are in the items already
+			delete(ch.items, ev.Feed)
+		case EventRead, EventBookmark:
+			for _, item := range f.RssItems {
+				if item.Key() != ev.Key || item.Pending() {
+					continue
+				}
+				if ev.Type == EventRead {
+					item.SetRead(ev.Read)
+				} else {
+					item.SetBookmark(ev.Bookmark)
+				}
+				item.MarkSynced()
+			}
+		}
+	}
+
+	l.RefreshBookmarks()
+	return added
+}
+
+func (f Feed) toFeed(category string) *rss.RssFeed {
+	feed := &rss.RssFeed{Url: f.Url, Category: category, Error: f.Error}
+	if f.FetchedAt != nil {
+		feed.Feed = &gofeed.Feed{
+			Title:       f.Title,
+			Link:        f.Link,
+			Description: f.Description,
+			FeedLink:    f.Url,
+		}
+	}
+	return feed
+}
+
+func toItem(r rss.ItemRecord) *rss.RssItem {
+	item := &gofeed.Item{
+		GUID:            r.Key,
+		Title:           r.Title,
+		Link:            r.Link,
+		Content:         r.Content,
+		PublishedParsed: r.Published,
+		UpdatedParsed:   r.Updated,
+	}
+	if r.Author != "" {
+		item.Author = &gofeed.Person{Name: r.Author}
+	}
+
+	return &rss.RssItem{
+		Item:      item,
+		Read:      r.Read,
+		Bookmark:  r.Bookmark,
+		FirstSeen: r.FirstSeen,
+		ReadAt:    r.ReadAt,
+	}
+}
@@ -0,0 +1,240 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/emilosman/rssboat/internal/rss"
+)
+
+// Server owns the list for rssboat daemon. It refreshes feeds, saves the
+// cache and serves the list to clients over a Unix socket, see Service for
+// the methods.
+type Server struct {
+	List *rss.List
+	// Save persists the list, called by Flush after changes
+	Save func() error
+	// Urls reads the feeds of urls.yaml again, for Reconcile
+	Urls func() ([]*rss.RssFeed, error)
+
+	mu     sync.Mutex
+	dirty  bool
+	events *eventLog
+}
+
+func NewServer(l *rss.List) *Server {
+	return &Server{List: l, events: newEventLog()}
+}
+
+// SocketPath is where the daemon listens, in XDG_RUNTIME_DIR when set or the
+// cache dir otherwise
+func SocketPath() (string, error) {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "rssboat.sock"), nil
+	}
+	dir, err := rss.CacheDirPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "rssboat.sock"), nil
+}
+
+// Listen opens the socket at path. A socket left behind by a daemon that
+// didn't stop cleanly is replaced, one that still answers is not.
+func Listen(path string) (net.Listener, error) {
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return nil, ErrAlreadyRunning
+	}
+	os.Remove(path)
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// Serve answers JSON-RPC 1.0 requests, one JSON object per call, on every
+// connection until the listener is closed
+func (s *Server) Serve(ln net.Listener) error {
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("Rssboat", &Service{s: s}); err != nil {
+		return err
+	}
+
+	for {
+		conn, err := ln.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		go srv.ServeCodec(jsonrpc.NewServerCodec(conn))
+	}
+}
+
+// Flush saves the list if it changed since the last save
+func (s *Server) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dirty || s.Save == nil {
+		return nil
+	}
+	if err := s.Save(); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
+
+// Refresh fetches the feeds asked for, feeds that are due by default. Feeds
+// are fetched outside the lock, so clients aren't kept waiting.
+func (s *Server) Refresh(args RefreshArgs) (RefreshReply, error) {
+	s.mu.Lock()
+	feeds, err := s.refreshFeeds(args)
+	copies := make([]*rss.RssFeed, len(feeds))
+	for i, f := range feeds {
+		copies[i] = &rss.RssFeed{Url: f.Url, Options: f.Options}
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return RefreshReply{}, err
+	}
+
+	type fetched struct {
+		feed, copy *rss.RssFeed
+		err        error
+	}
+	results := make(chan fetched, len(feeds))
+	for i := range feeds {
+		go func(f, c *rss.RssFeed) {
+			results <- fetched{f, c, c.GetFeed()}
+		}(feeds[i], copies[i])
+	}
+
+	reply := RefreshReply{Feeds: len(feeds), Errors: map[string]string{}}
+	for range feeds {
+		res := <-results
+		ev := Event{Type: EventRefreshed, Feed: res.feed.Url}
+
+		s.mu.Lock()
+		if res.err != nil {
+			res.feed.Error = res.err.Error()
+			ev.Error = res.feed.Error
+			reply.Errors[res.feed.Url] = ev.Error
+		} else {
+			ev.New = res.feed.MergeFetched(res.copy)
+			reply.New += ev.New
+		}
+		s.dirty = true
+		s.mu.Unlock()
+
+		s.events.publish(ev)
+	}
+
+	s.mu.Lock()
+	s.List.RefreshBookmarks()
+	s.mu.Unlock()
+	return reply, nil
+}
+
+func (s *Server) refreshFeeds(args RefreshArgs) ([]*rss.RssFeed, error) {
+	var feeds []*rss.RssFeed
+	switch {
+	case len(args.Feeds) > 0:
+		for _, url := range args.Feeds {
+			feed, err := s.feed(url)
+			if err != nil {
+				return nil, err
+			}
+			feeds = append(feeds, feed)
+		}
+	case args.Category != "":
+		if !slices.Contains(s.List.Categories(), args.Category) {
+			return nil, fmt.Errorf("%w: %s", rss.ErrCategoryNotFound, args.Category)
+		}
+		feeds, _ = s.List.GetCategory(args.Category)
+	default:
+		for _, feed := range s.visibleFeeds() {
+			if args.All || feed.Due() {
+				feeds = append(feeds, feed)
+			}
+		}
+	}
+	return feeds, nil
+}
+
+// Reconcile applies changes to urls.yaml and fetches the feeds it added
+func (s *Server) Reconcile() error {
+	feeds, err := s.Urls()
+	if err != nil && !errors.Is(err, rss.ErrInclude) {
+		return err
+	}
+
+	s.mu.Lock()
+	added, removed := s.List.Reconcile(feeds)
+	s.dirty = true
+	s.mu.Unlock()
+
+	if len(added) > 0 || len(removed) > 0 {
+		s.events.publish(Event{Type: EventFeeds})
+	}
+	if len(added) > 0 {
+		var urls []string
+		for _, f := range added {
+			urls = append(urls, f.Url)
+		}
+		if _, refreshErr := s.Refresh(RefreshArgs{Feeds: urls}); refreshErr != nil {
+			return refreshErr
+		}
+	}
+	return err
+}
+
+// feed returns a feed by URL, must be called with the lock held
+func (s *Server) feed(url string) (*rss.RssFeed, error) {
+	feed, ok := s.List.FeedIndex[url]
+	if !ok || feed == s.List.Bookmarks() {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFeed, url)
+	}
+	return feed, nil
+}
+
+// item returns an item by feed URL and key, must be called with the lock held
+func (s *Server) item(url, key string) (*rss.RssItem, error) {
+	feed, err := s.feed(url)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range feed.RssItems {
+		if item.Key() == key {
+			return item, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownItem, key)
+}
+
+func (s *Server) visibleFeeds() []*rss.RssFeed {
+	var feeds []*rss.RssFeed
+	for _, feed := range s.List.Feeds {
+		if feed != s.List.Bookmarks() && !feed.Options.Hidden {
+			feeds = append(feeds, feed)
+		}
+	}
+	return feeds
+}
@@ -0,0 +1,212 @@
+package daemon
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/emilosman/rssboat/internal/rss"
+)
+
+const testFeed = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Example</title><link>https://example.com/</link>
+<item><title>First</title><link>https://example.com/1</link><guid>1</guid></item>
+<item><title>Second</title><link>https://example.com/2</link><guid>2</guid></item>
+</channel></rss>`
+
+func startServer(t *testing.T) (*Server, *Client, string) {
+	t.Helper()
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	feeds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testFeed))
+	}))
+	t.Cleanup(feeds.Close)
+
+	l := rss.NewListWithDefaults()
+	l.AddFeeds(&rss.RssFeed{Url: feeds.URL, Category: "news"})
+	s := NewServer(l)
+
+	path := filepath.Join(t.TempDir(), "rssboat.sock")
+	ln, err := Listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go s.Serve(ln)
+
+	c, err := Dial(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	return s, c, path
+}
+
+func TestDaemon(t *testing.T) {
+	t.Run("Should refresh feeds and list items over the socket", func(t *testing.T) {
+		s, c, _ := startServer(t)
+		url := s.List.Feeds[1].Url
+
+		var refreshed RefreshReply
+		if err := c.Call("Refresh", RefreshArgs{}, &refreshed); err != nil {
+			t.Fatalf("Unexpected error refreshing: %q", err)
+		}
+		if refreshed.Feeds != 1 || refreshed.New != 2 || len(refreshed.Errors) != 0 {
+			t.Errorf("Wrong refresh reply: %+v", refreshed)
+		}
+
+		var items []rss.ItemRecord
+		if err := c.Call("Items", ItemsArgs{Unread: true}, &items); err != nil {
+			t.Fatalf("Unexpected error listing items: %q", err)
+		}
+		if len(items) != 2 || items[0].FeedUrl != url {
+			t.Errorf("Expected two unread items, got %v", items)
+		}
+
+		var events []Event
+		if err := c.Call("Events", EventsArgs{}, &events); err != nil {
+			t.Fatalf("Unexpected error polling events: %q", err)
+		}
+		if len(events) != 1 || events[0].Type != EventRefreshed || events[0].New != 2 {
+			t.Errorf("Expected a refreshed event, got %v", events)
+		}
+
+		if err := c.Call("Events", EventsArgs{After: events[0].Seq, Wait: "10ms"}, &events); err != nil || len(events) != 0 {
+			t.Errorf("Expected no new events, got %v %v", events, err)
+		}
+	})
+
+	t.Run("Should change item state and save it on flush", func(t *testing.T) {
+		s, c, _ := startServer(t)
+		saves := 0
+		s.Save = func() error { saves++; return nil }
+		url := s.List.Feeds[1].Url
+		c.Call("Refresh", RefreshArgs{}, &RefreshReply{})
+
+		var state bool
+		if err := c.Call("SetBookmark", ItemArgs{Feed: url, Key: "1", Bookmark: true}, &state); err != nil {
+			t.Fatalf("Unexpected error bookmarking: %q", err)
+		}
+		var marked int
+		if err := c.Call("MarkRead", ItemsArgs{Feed: url}, &marked); err != nil || marked != 2 {
+			t.Errorf("Expected two items marked read, got %d %v", marked, err)
+		}
+		if err := c.Call("SetRead", ItemArgs{Feed: url, Key: "2", Read: false}, &state); err != nil {
+			t.Fatalf("Unexpected error marking unread: %q", err)
+		}
+
+		feed := s.List.FeedIndex[url]
+		if unread := feed.UnreadCount(); unread != 1 {
+			t.Errorf("Expected one unread item, got %d", unread)
+		}
+		if len(s.List.Bookmarks().RssItems) != 1 {
+			t.Error("Bookmarked item should be in bookmarks")
+		}
+
+		s.Flush()
+		s.Flush()
+		if saves != 1 {
+			t.Errorf("Expected one save, got %d", saves)
+		}
+
+		err := c.Call("SetRead", ItemArgs{Feed: url, Key: "none"}, &state)
+		if err == nil || err.Error() != ErrUnknownItem.Error()+": none" {
+			t.Errorf("Expected unknown item error, got %v", err)
+		}
+	})
+
+	t.Run("Should attach as a backend", func(t *testing.T) {
+		s, c, _ := startServer(t)
+		url := s.List.Feeds[1].Url
+		c.Call("Refresh", RefreshArgs{}, &RefreshReply{})
+
+		l, err := rss.LoadRemoteList(c)
+		if err != nil {
+			t.Fatalf("Unexpected error loading: %q", err)
+		}
+		if err := c.Pull(l); err != nil {
+			t.Fatalf("Unexpected error pulling: %q", err)
+		}
+
+		feed := l.FeedIndex[url]
+		if feed == nil || feed.Name() != "Example" || len(feed.RssItems) != 2 {
+			t.Fatalf("Expected the daemon's feed and items, got %+v", feed)
+		}
+
+		feed.RssItems[0].MarkRead()
+		if err := l.PushChanges(); err != nil {
+			t.Fatalf("Unexpected error pushing: %q", err)
+		}
+		if s.List.FeedIndex[url].UnreadCount() != 1 {
+			t.Error("Read state should be sent to the daemon")
+		}
+
+		if err := feed.GetFeed(); err != nil {
+			t.Fatalf("Unexpected error syncing: %q", err)
+		}
+		if feed.UnreadCount() != 1 {
+			t.Errorf("Synced feed should keep its state, got %d unread", feed.UnreadCount())
+		}
+	})
+
+	t.Run("Should poll what other clients and refreshes changed", func(t *testing.T) {
+		s, c, path := startServer(t)
+		url := s.List.Feeds[1].Url
+
+		l, _ := rss.LoadRemoteList(c)
+		if err := c.Pull(l); err != nil {
+			t.Fatalf("Unexpected error pulling: %q", err)
+		}
+
+		other, err := Dial(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer other.Close()
+		other.Call("Refresh", RefreshArgs{}, &RefreshReply{})
+
+		changes, err := c.Poll(10 * time.Millisecond)
+		if err != nil {
+			t.Fatalf("Unexpected error polling: %q", err)
+		}
+		if added := changes.Apply(l); added != 2 || len(l.FeedIndex[url].RssItems) != 2 {
+			t.Fatalf("Expected the refreshed items, got %d added", added)
+		}
+
+		feed := l.FeedIndex[url]
+		feed.RssItems[1].MarkRead()
+		var state bool
+		other.Call("SetRead", ItemArgs{Feed: url, Key: feed.RssItems[0].Key(), Read: true}, &state)
+		other.Call("SetBookmark", ItemArgs{Feed: url, Key: feed.RssItems[1].Key(), Bookmark: true}, &state)
+
+		changes, err = c.Poll(10 * time.Millisecond)
+		if err != nil {
+			t.Fatalf("Unexpected error polling: %q", err)
+		}
+		changes.Apply(l)
+		if !feed.RssItems[0].Read || feed.RssItems[0].Pending() {
+			t.Error("Item read in another client should be read")
+		}
+		if feed.RssItems[1].Bookmark || !feed.RssItems[1].Pending() {
+			t.Error("Change not pushed yet should be kept")
+		}
+
+		changes, err = c.Poll(10 * time.Millisecond)
+		if err != nil || len(changes.Events) != 0 {
+			t.Errorf("Expected no new events, got %v %v", changes, err)
+		}
+	})
+
+	t.Run("Should not start twice on one socket", func(t *testing.T) {
+		_, _, path := startServer(t)
+
+		if _, err := Listen(path); !errors.Is(err, ErrAlreadyRunning) {
+			t.Errorf("Expected ErrAlreadyRunning, got %v", err)
+		}
+	})
+}
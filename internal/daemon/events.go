@@ -0,0 +1,86 @@
+package daemon
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	EventRefreshed = "refreshed"
+	EventRead      = "read"
+	EventBookmark  = "bookmark"
+	// EventFeeds is sent when urls.yaml changed the list of feeds
+	EventFeeds = "feeds"
+)
+
+// Events kept for clients that poll late
+const eventsKept = 256
+
+type Event struct {
+	Seq      int64     `json:"seq"`
+	Time     time.Time `json:"time"`
+	Type     string    `json:"type"`
+	Feed     string    `json:"feed,omitempty"`
+	Key      string    `json:"key,omitempty"`
+	New      int       `json:"new,omitempty"`
+	Read     bool      `json:"read"`
+	Bookmark bool      `json:"bookmark"`
+	Error    string    `json:"error,omitempty"`
+}
+
+type eventLog struct {
+	mu     sync.Mutex
+	seq    int64
+	events []Event
+	// closed and replaced when an event is published
+	changed chan struct{}
+}
+
+func newEventLog() *eventLog {
+	return &eventLog{changed: make(chan struct{})}
+}
+
+func (e *eventLog) publish(ev Event) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.seq++
+	ev.Seq = e.seq
+	ev.Time = time.Now()
+	e.events = append(e.events, ev)
+	if len(e.events) > eventsKept {
+		e.events = e.events[len(e.events)-eventsKept:]
+	}
+
+	close(e.changed)
+	e.changed = make(chan struct{})
+}
+
+// wait returns the events after seq, waiting up to timeout for one
+func (e *eventLog) wait(after int64, timeout time.Duration) []Event {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		e.mu.Lock()
+		var events []Event
+		for _, ev := range e.events {
+			if ev.Seq > after {
+				events = append(events, ev)
+			}
+		}
+		changed := e.changed
+		e.mu.Unlock()
+
+		if len(events) > 0 {
+			return events
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package daemon
+
+import "errors"
+
+var (
+	ErrAlreadyRunning = errors.New("rssboat daemon is already running")
+	ErrUnknownFeed    = errors.New("Unknown feed")
+	ErrUnknownItem    = errors.New("Unknown item")
+)
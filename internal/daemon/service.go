@@ -0,0 +1,255 @@
+package daemon
+
+import (
+	"time"
+
+	"github.com/emilosman/rssboat/internal/rss"
+)
+
+// Longest a client can wait for events
+const maxEventsWait = 5 * time.Minute
+
+// Service holds the methods clients call, as "Rssboat.<Method>" with the
+// arguments as the first and only param, such as
+//
+//	{"id": 1, "method": "Rssboat.Items", "params": [{"unread": true}]}
+type Service struct {
+	s *Server
+}
+
+type FeedsArgs struct {
+	Category string `json:"category"`
+	// Feed is a feed URL
+	Feed   string `json:"feed"`
+	Unread bool   `json:"unread"`
+}
+
+type Feed struct {
+	Url         string     `json:"url"`
+	Title       string     `json:"title"`
+	Link        string     `json:"link,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Categories  []string   `json:"categories"`
+	Unread      int        `json:"unread"`
+	Total       int        `json:"total"`
+	Error       string     `json:"error,omitempty"`
+	FetchedAt   *time.Time `json:"fetched_at,omitempty"`
+}
+
+type ItemsArgs struct {
+	Category   string `json:"category"`
+	Feed       string `json:"feed"`
+	Unread     bool   `json:"unread"`
+	Bookmarked bool   `json:"bookmarked"`
+	Tag        string `json:"tag"`
+	// Since is a duration such as 24h or 7d
+	Since   string `json:"since"`
+	Content bool   `json:"content"`
+}
+
+type ItemArgs struct {
+	Feed     string `json:"feed"`
+	Key      string `json:"key"`
+	Read     bool   `json:"read"`
+	Bookmark bool   `json:"bookmark"`
+}
+
+type RefreshArgs struct {
+	// Feeds are feed URLs
+	Feeds    []string `json:"feeds"`
+	Category string   `json:"category"`
+	// All refreshes feeds that aren't due yet too
+	All bool `json:"all"`
+}
+
+type RefreshReply struct {
+	Feeds int `json:"feeds"`
+	New   int `json:"new"`
+	// Errors by feed URL
+	Errors map[string]string `json:"errors"`
+}
+
+type EventsArgs struct {
+	// After is the seq of the last event seen, 0 for every event kept
+	After int64 `json:"after"`
+	// Wait is how long to wait for an event, such as 30s, 30s by default
+	Wait string `json:"wait"`
+}
+
+// Feeds lists feeds with their unread counts, leaving out hidden feeds
+func (svc *Service) Feeds(args FeedsArgs, reply *[]Feed) error {
+	s := svc.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	feeds := s.visibleFeeds()
+	switch {
+	case args.Feed != "":
+		feed, err := s.feed(args.Feed)
+		if err != nil {
+			return err
+		}
+		feeds = []*rss.RssFeed{feed}
+	case args.Category != "":
+		feeds, _ = s.List.GetCategory(args.Category)
+	}
+
+	*reply = []Feed{}
+	for _, f := range feeds {
+		if args.Unread && !f.HasUnread() {
+			continue
+		}
+		feed := Feed{
+			Url:        f.Url,
+			Title:      f.Name(),
+			Categories: f.Categories,
+			Unread:     f.UnreadCount(),
+			Total:      len(f.RssItems),
+			Error:      f.Error,
+			FetchedAt:  f.FetchedAt,
+		}
+		if f.Feed != nil {
+			feed.Link = f.Feed.Link
+			feed.Description = f.Feed.Description
+		}
+		*reply = append(*reply, feed)
+	}
+	return nil
+}
+
+// Items lists items newest first, like rssboat items
+func (svc *Service) Items(args ItemsArgs, reply *[]rss.ItemRecord) error {
+	q, err := args.query()
+	if err != nil {
+		return err
+	}
+
+	s := svc.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items, err := s.List.Items(q)
+	if err != nil {
+		return err
+	}
+	*reply = make([]rss.ItemRecord, 0, len(items))
+	for _, fi := range items {
+		*reply = append(*reply, fi.Record(args.Content))
+	}
+	return nil
+}
+
+// MarkRead marks the items matching args read and replies how many changed
+func (svc *Service) MarkRead(args ItemsArgs, reply *int) error {
+	q, err := args.query()
+	if err != nil {
+		return err
+	}
+	q.Unread = true
+
+	s := svc.s
+	s.mu.Lock()
+	items, err := s.List.Items(q)
+	for _, fi := range items {
+		fi.Item.SetRead(true)
+	}
+	s.dirty = s.dirty || len(items) > 0
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range items {
+		s.events.publish(Event{Type: EventRead, Feed: fi.Feed.Url, Key: fi.Item.Key(), Read: true})
+	}
+	*reply = len(items)
+	return nil
+}
+
+// SetRead marks one item read or unread
+func (svc *Service) SetRead(args ItemArgs, reply *bool) error {
+	s := svc.s
+	s.mu.Lock()
+	item, err := s.item(args.Feed, args.Key)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	changed := item.Read != args.Read
+	item.SetRead(args.Read)
+	s.dirty = s.dirty || changed
+	s.mu.Unlock()
+
+	if changed {
+		s.events.publish(Event{Type: EventRead, Feed: args.Feed, Key: args.Key, Read: args.Read})
+	}
+	*reply = args.Read
+	return nil
+}
+
+// SetBookmark adds one item to bookmarks or removes it
+func (svc *Service) SetBookmark(args ItemArgs, reply *bool) error {
+	s := svc.s
+	s.mu.Lock()
+	item, err := s.item(args.Feed, args.Key)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	changed := item.Bookmark != args.Bookmark
+	if changed {
+		s.List.ToggleBookmark(item)
+		s.dirty = true
+	}
+	s.mu.Unlock()
+
+	if changed {
+		s.events.publish(Event{Type: EventBookmark, Feed: args.Feed, Key: args.Key, Bookmark: args.Bookmark})
+	}
+	*reply = args.Bookmark
+	return nil
+}
+
+// Refresh fetches feeds and replies once they are done
+func (svc *Service) Refresh(args RefreshArgs, reply *RefreshReply) error {
+	r, err := svc.s.Refresh(args)
+	*reply = r
+	return err
+}
+
+// Events replies with the events after args.After, waiting for one when
+// there are none yet. An empty reply means none came in time.
+func (svc *Service) Events(args EventsArgs, reply *[]Event) error {
+	wait := 30 * time.Second
+	if args.Wait != "" {
+		d, err := rss.ParseDuration(args.Wait)
+		if err != nil {
+			return err
+		}
+		wait = min(d, maxEventsWait)
+	}
+
+	*reply = svc.s.events.wait(args.After, wait)
+	if *reply == nil {
+		*reply = []Event{}
+	}
+	return nil
+}
+
+func (args ItemsArgs) query() (rss.ItemQuery, error) {
+	q := rss.ItemQuery{
+		Category:   args.Category,
+		Feed:       args.Feed,
+		Unread:     args.Unread,
+		Bookmarked: args.Bookmarked,
+		Tag:        args.Tag,
+	}
+	if args.Since != "" {
+		d, err := rss.ParseDuration(args.Since)
+		if err != nil {
+			return q, err
+		}
+		q.Since = time.Now().Add(-d)
+	}
+	return q, nil
+}
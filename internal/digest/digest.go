@@ -0,0 +1,155 @@
+package digest
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/emilosman/rssboat/internal/rss"
+)
+
+// Longest summary of an item, in characters
+const summaryLength = 280
+
+// Digest is a list's new items grouped by category and feed, for an email
+type Digest struct {
+	// Category is the category asked for, empty for every category
+	Category string
+	Since    time.Time
+	Date     time.Time
+	// DateFormat is the Go time layout item dates are shown in
+	DateFormat string
+	Categories []Category
+	Count      int
+
+	items []rss.FeedItem
+}
+
+type Category struct {
+	Name  string
+	Feeds []Feed
+}
+
+type Feed struct {
+	Title string
+	Url   string
+	Items []Item
+}
+
+type Item struct {
+	Title   string
+	Link    string
+	Date    *time.Time
+	Summary string
+}
+
+// New collects the items matching q. Categories and feeds keep the order of
+// urls.yaml, items come newest first.
+func New(l *rss.List, q rss.ItemQuery) (*Digest, error) {
+	items, err := l.Items(q)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Digest{
+		Category:   q.Category,
+		Since:      q.Since,
+		Date:       time.Now(),
+		DateFormat: rss.DefaultDateFormat,
+		Count:      len(items),
+		items:      items,
+	}
+
+	byFeed := map[*rss.RssFeed][]Item{}
+	for _, fi := range items {
+		byFeed[fi.Feed] = append(byFeed[fi.Feed], newItem(fi.Item))
+	}
+
+	for _, f := range l.Feeds {
+		found, ok := byFeed[f]
+		if !ok {
+			continue
+		}
+		delete(byFeed, f)
+
+		name := category(f, q.Category)
+		i := slices.IndexFunc(d.Categories, func(c Category) bool { return c.Name == name })
+		if i < 0 {
+			d.Categories = append(d.Categories, Category{Name: name})
+			i = len(d.Categories) - 1
+		}
+		d.Categories[i].Feeds = append(d.Categories[i].Feeds, Feed{Title: f.Name(), Url: f.Url, Items: found})
+	}
+
+	return d, nil
+}
+
+// Subject is the subject of the digest's email
+func (d *Digest) Subject() string {
+	if d.Category != "" {
+		return fmt.Sprintf("rssboat: %s in %s", d.newItems(), d.Category)
+	}
+	return "rssboat: " + d.newItems()
+}
+
+// Heading opens the digest, with the time it covers
+func (d *Digest) Heading() string {
+	if d.Since.IsZero() {
+		return d.newItems()
+	}
+	return fmt.Sprintf("%s since %s", d.newItems(), d.Since.Local().Format(d.DateFormat))
+}
+
+func (d *Digest) newItems() string {
+	if d.Count == 1 {
+		return "1 new item"
+	}
+	return fmt.Sprintf("%d new items", d.Count)
+}
+
+// MarkRead marks the items of the digest read
+func (d *Digest) MarkRead() {
+	for _, fi := range d.items {
+		fi.Item.SetRead(true)
+	}
+}
+
+// category is the category a feed is listed under, the one asked for when
+// the feed is in several
+func category(f *rss.RssFeed, want string) string {
+	if want != "" {
+		for _, c := range f.Categories {
+			if c == want || strings.HasPrefix(c, want+rss.CategorySeparator) {
+				return c
+			}
+		}
+	}
+	return f.Category
+}
+
+func newItem(i *rss.RssItem) Item {
+	item := Item{Link: i.Link(), Date: i.Date()}
+	if i.Item == nil {
+		return item
+	}
+
+	item.Title = i.Item.Title
+	item.Summary = summarize(i.Description())
+	return item
+}
+
+// summarize puts text on one line and cuts it after summaryLength
+// characters, at a space when there is one
+func summarize(text string) string {
+	summary := []rune(strings.Join(strings.Fields(text), " "))
+	if len(summary) <= summaryLength {
+		return string(summary)
+	}
+
+	cut := string(summary[:summaryLength])
+	if space := strings.LastIndex(cut, " "); space > 0 {
+		cut = cut[:space]
+	}
+	return cut + "…"
+}
@@ -0,0 +1,254 @@
+package digest
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emilosman/rssboat/internal/config"
+	"github.com/emilosman/rssboat/internal/rss"
+	"github.com/mmcdole/gofeed"
+)
+
+// smtpServer is a fake SMTP server accepting one message
+type smtpServer struct {
+	addr     string
+	auth     string
+	from     string
+	to       []string
+	data     string
+	received chan struct{}
+}
+
+func startSmtpServer(t *testing.T) *smtpServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	s := &smtpServer{addr: ln.Addr().String(), received: make(chan struct{})}
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		s.serve(conn)
+	}()
+	return s
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	reply("220 fake ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(cmd) {
+		case "EHLO":
+			reply("250-fake")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			s.auth = arg
+			reply("235 Authenticated")
+		case "MAIL":
+			s.from = arg
+			reply("250 OK")
+		case "RCPT":
+			s.to = append(s.to, arg)
+			reply("250 OK")
+		case "DATA":
+			reply("354 Go ahead")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil || line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.data = data.String()
+			reply("250 Queued")
+			close(s.received)
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Not implemented")
+		}
+	}
+}
+
+func TestDigest(t *testing.T) {
+	now := time.Now()
+	hoursAgo := func(n int) *time.Time {
+		d := now.Add(time.Duration(-n) * time.Hour)
+		return &d
+	}
+
+	newDigestList := func() *rss.List {
+		l := rss.NewListWithDefaults()
+		l.AddFeeds(
+			&rss.RssFeed{Url: "https://example.com/work.xml", Category: "work", Feed: &gofeed.Feed{Title: "Work news"}, RssItems: []*rss.RssItem{
+				{Item: &gofeed.Item{GUID: "1", Title: "Standup moved", Link: "https://example.com/1", Description: "To 10 <sharp>", PublishedParsed: hoursAgo(1)}},
+				{Item: &gofeed.Item{GUID: "2", Title: "Read already", PublishedParsed: hoursAgo(2)}, Read: true},
+				{Item: &gofeed.Item{GUID: "3", Title: "Last week", PublishedParsed: hoursAgo(24 * 7)}},
+			}},
+			&rss.RssFeed{Url: "https://example.com/go.xml", Category: "tech", Feed: &gofeed.Feed{Title: "Go blog"}, RssItems: []*rss.RssItem{
+				{Item: &gofeed.Item{GUID: "4", Title: "Go 2", Link: "https://example.com/4", PublishedParsed: hoursAgo(3)}},
+			}},
+		)
+		return l
+	}
+
+	t.Run("Should group new unread items by category and feed", func(t *testing.T) {
+		d, err := New(newDigestList(), rss.ItemQuery{Unread: true, Since: *hoursAgo(24)})
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		if d.Count != 2 || len(d.Categories) != 2 {
+			t.Fatalf("Expected two items in two categories, got %+v", d)
+		}
+		work := d.Categories[0]
+		if work.Name != "work" || work.Feeds[0].Title != "Work news" || len(work.Feeds[0].Items) != 1 {
+			t.Errorf("Wrong first category, got %+v", work)
+		}
+		if d.Subject() != "rssboat: 2 new items" {
+			t.Errorf("Wrong subject, got %q", d.Subject())
+		}
+
+		d, _ = New(newDigestList(), rss.ItemQuery{Category: "work", Unread: true})
+		if d.Count != 2 || d.Subject() != "rssboat: 2 new items in work" {
+			t.Errorf("Expected unread work items, got %d and %q", d.Count, d.Subject())
+		}
+	})
+
+	t.Run("Should render text and HTML", func(t *testing.T) {
+		d, _ := New(newDigestList(), rss.ItemQuery{Unread: true, Since: *hoursAgo(24)})
+
+		text, err := d.Text()
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		for _, want := range []string{"== work ==", "Work news", "- Standup moved", "  https://example.com/1", "  To 10 <sharp>", "== tech =="} {
+			if !strings.Contains(text, want) {
+				t.Errorf("Text should contain %q, got:\n%s", want, text)
+			}
+		}
+
+		html, err := d.HTML()
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		for _, want := range []string{"<h2>work</h2>", "<h3>Work news</h3>", `<a href="https://example.com/1">Standup moved</a>`, "To 10 &lt;sharp&gt;"} {
+			if !strings.Contains(html, want) {
+				t.Errorf("HTML should contain %q, got:\n%s", want, html)
+			}
+		}
+	})
+
+	t.Run("Should shorten long summaries at a space", func(t *testing.T) {
+		long := strings.Repeat("word ", 100)
+		summary := summarize(long)
+		if len([]rune(summary)) > summaryLength+1 || !strings.HasSuffix(summary, "d…") {
+			t.Errorf("Wrong summary, got %q", summary)
+		}
+		if summarize("short\n text") != "short text" {
+			t.Error("Short text should be kept on one line")
+		}
+	})
+
+	t.Run("Should send the email to the SMTP server", func(t *testing.T) {
+		server := startSmtpServer(t)
+		host, port, _ := net.SplitHostPort(server.addr)
+		smtp := config.SMTP{Host: host, Username: "me", Password: "secret"}
+		smtp.Port, _ = strconv.Atoi(port)
+
+		d, _ := New(newDigestList(), rss.ItemQuery{Unread: true, Since: *hoursAgo(24)})
+		to := []string{"Me <me@example.com>"}
+		msg, err := d.Message("rssboat@example.com", to)
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if err := Send(smtp, "rssboat@example.com", to, msg); err != nil {
+			t.Fatalf("Unexpected error sending: %q", err)
+		}
+		<-server.received
+
+		if server.from != "FROM:<rssboat@example.com>" || len(server.to) != 1 || server.to[0] != "TO:<me@example.com>" {
+			t.Errorf("Wrong envelope, got %q %q", server.from, server.to)
+		}
+		if server.auth == "" {
+			t.Error("Should log in with the username")
+		}
+
+		m, err := mail.ReadMessage(strings.NewReader(server.data))
+		if err != nil {
+			t.Fatalf("Unexpected error reading the message: %q", err)
+		}
+		if m.Header.Get("Subject") != "rssboat: 2 new items" {
+			t.Errorf("Wrong subject, got %q", m.Header.Get("Subject"))
+		}
+
+		mediaType, params, _ := mime.ParseMediaType(m.Header.Get("Content-Type"))
+		if mediaType != "multipart/alternative" {
+			t.Fatalf("Expected multipart/alternative, got %q", mediaType)
+		}
+		mr := multipart.NewReader(m.Body, params["boundary"])
+		var types []string
+		for {
+			part, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			body, _ := io.ReadAll(part)
+			types = append(types, part.Header.Get("Content-Type"))
+			if !strings.Contains(string(body), "Standup moved") {
+				t.Errorf("Part should contain the item, got %q", body)
+			}
+		}
+		if len(types) != 2 || !strings.HasPrefix(types[0], "text/plain") || !strings.HasPrefix(types[1], "text/html") {
+			t.Errorf("Expected text and HTML parts, got %v", types)
+		}
+	})
+
+	t.Run("Should need a server and recipients", func(t *testing.T) {
+		if err := Send(config.SMTP{Port: 587}, "a@example.com", []string{"b@example.com"}, nil); !errors.Is(err, ErrNoSmtpHost) {
+			t.Errorf("Expected ErrNoSmtpHost, got %v", err)
+		}
+		if err := Send(config.SMTP{Host: "localhost", Port: 587}, "a@example.com", nil, nil); !errors.Is(err, ErrNoRecipients) {
+			t.Errorf("Expected ErrNoRecipients, got %v", err)
+		}
+	})
+
+	t.Run("Should mark the items of the digest read", func(t *testing.T) {
+		l := newDigestList()
+		d, _ := New(l, rss.ItemQuery{Unread: true, Since: *hoursAgo(24)})
+		d.MarkRead()
+
+		if l.FeedIndex["https://example.com/work.xml"].UnreadCount() != 1 {
+			t.Error("Only items in the digest should be marked read")
+		}
+		if l.FeedIndex["https://example.com/go.xml"].HasUnread() {
+			t.Error("Items in the digest should be marked read")
+		}
+	})
+}
@@ -0,0 +1,236 @@
+package digest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/emilosman/rssboat/internal/config"
+)
+
+var funcs = map[string]any{
+	"date": func(format string, t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Local().Format(format)
+	},
+}
+
+var textTemplate = template.Must(template.New("text").Funcs(funcs).Parse(
+	`{{.Heading}}
+{{- range .Categories}}
+
+== {{.Name}} ==
+{{- range .Feeds}}
+
+{{.Title}}
+{{- range .Items}}
+- {{.Title}}{{with date $.DateFormat .Date}} ({{.}}){{end}}
+{{- with .Link}}
+  {{.}}{{end}}
+{{- with .Summary}}
+  {{.}}{{end}}
+{{- end}}{{end}}{{end}}
+`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(funcs).Parse(
+	`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Subject}}</title></head>
+<body style="font-family: sans-serif; max-width: 40em">
+<p>{{.Heading}}</p>
+{{range .Categories}}<h2>{{.Name}}</h2>
+{{range .Feeds}}<h3>{{.Title}}</h3>
+<ul>
+{{range .Items}}<li>
+{{if .Link}}<a href="{{.Link}}">{{.Title}}</a>{{else}}{{.Title}}{{end}}
+{{with date $.DateFormat .Date}}<small>{{.}}</small>{{end}}
+{{with .Summary}}<p>{{.}}</p>{{end}}
+</li>
+{{end}}</ul>
+{{end}}{{end}}</body>
+</html>
+`))
+
+// Text renders the digest as plain text
+func (d *Digest) Text() (string, error) {
+	var buf bytes.Buffer
+	err := textTemplate.Execute(&buf, d)
+	return buf.String(), err
+}
+
+// HTML renders the digest as an HTML page
+func (d *Digest) HTML() (string, error) {
+	var buf bytes.Buffer
+	err := htmlTemplate.Execute(&buf, d)
+	return buf.String(), err
+}
+
+// Message renders the digest as an email with a plain text and an HTML part,
+// ready to send or save as an .eml file
+func (d *Digest) Message(from string, to []string) ([]byte, error) {
+	text, err := d.Text()
+	if err != nil {
+		return nil, err
+	}
+	html, err := d.HTML()
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qw := quotedprintable.NewWriter(w)
+		if _, err := io.WriteString(qw, part.content); err != nil {
+			return nil, err
+		}
+		if err := qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	headers := [][2]string{
+		{"From", from},
+		{"To", strings.Join(to, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", d.Subject())},
+		{"Date", d.Date.Format(time.RFC1123Z)},
+		{"Message-ID", messageID(from)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + mw.Boundary()},
+	}
+	for _, h := range headers {
+		if h[1] != "" {
+			fmt.Fprintf(&msg, "%s: %s\r\n", h[0], h[1])
+		}
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+func messageID(from string) string {
+	domain := "rssboat"
+	if _, host, ok := strings.Cut(address(from), "@"); ok {
+		domain = host
+	}
+	b := make([]byte, 12)
+	rand.Read(b)
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain)
+}
+
+// How long connecting to the SMTP server and the whole send may take
+const (
+	dialTimeout = 30 * time.Second
+	sendTimeout = 2 * time.Minute
+)
+
+// Send sends msg through the SMTP server, logging in when a username is set.
+// The password comes from RSSBOAT_SMTP_PASSWORD when set.
+func Send(server config.SMTP, from string, to []string, msg []byte) error {
+	if server.Host == "" {
+		return ErrNoSmtpHost
+	}
+	if len(to) == 0 {
+		return ErrNoRecipients
+	}
+	addr := net.JoinHostPort(server.Host, strconv.Itoa(server.Port))
+
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	var conn net.Conn
+	var err error
+	if server.Port == 465 {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: server.Host})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	// A server that stops answering fails the send rather than hanging cron
+	conn.SetDeadline(time.Now().Add(sendTimeout))
+
+	c, err := smtp.NewClient(conn, server.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if err := c.Hello("localhost"); err != nil {
+		return err
+	}
+	if ok, _ := c.Extension("STARTTLS"); ok && server.Port != 465 {
+		if err := c.StartTLS(&tls.Config{ServerName: server.Host}); err != nil {
+			return err
+		}
+	}
+	if server.Username != "" {
+		password := server.Password
+		if p := os.Getenv("RSSBOAT_SMTP_PASSWORD"); p != "" {
+			password = p
+		}
+		if err := c.Auth(smtp.PlainAuth("", server.Username, password, server.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(address(from)); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := c.Rcpt(address(addr)); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// address returns the bare address of "Name <address>"
+func address(s string) string {
+	if a, err := mail.ParseAddress(s); err == nil {
+		return a.Address
+	}
+	return s
+}
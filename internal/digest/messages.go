@@ -0,0 +1,8 @@
+package digest
+
+import "errors"
+
+var (
+	ErrNoSmtpHost   = errors.New("No SMTP server, set digest.smtp.host in config.yaml or write the digest to a file with -o")
+	ErrNoRecipients = errors.New("No one to send the digest to, set digest.to in config.yaml or use --to")
+)
@@ -0,0 +1,469 @@
+package fever
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emilosman/rssboat/internal/rss"
+)
+
+const (
+	apiVersion = 3
+	// Fever returns at most 50 items per request
+	itemsLimit = 50
+)
+
+type Server struct {
+	List   *rss.List
+	ApiKey string
+	// IdsFile is where item, feed and group ids are kept between runs
+	IdsFile string
+	// Save persists the list after read or saved state changes
+	Save func() error
+
+	mu        sync.Mutex
+	ids       *ids
+	entries   map[int64]entry
+	refreshed time.Time
+}
+
+type entry struct {
+	feed *rss.RssFeed
+	item *rss.RssItem
+}
+
+// ApiKey returns the key Fever clients send, the md5 of "email:password"
+func ApiKey(email, password string) string {
+	sum := md5.Sum([]byte(email + ":" + password))
+	return hex.EncodeToString(sum[:])
+}
+
+func NewServer(l *rss.List, apiKey, idsFile string) (*Server, error) {
+	s := &Server{
+		List:      l,
+		ApiKey:    apiKey,
+		IdsFile:   idsFile,
+		refreshed: time.Now(),
+	}
+
+	var err error
+	if idsFile != "" {
+		s.ids, err = loadIds(idsFile)
+	} else {
+		s.ids = newIds()
+	}
+
+	s.mu.Lock()
+	s.sync()
+	s.mu.Unlock()
+
+	return s, err
+}
+
+// Refresh fetches the feeds that are due and waits for them to finish.
+// Feeds are fetched outside the lock, so clients aren't kept waiting.
+func (s *Server) Refresh() error {
+	s.mu.Lock()
+	var feeds, copies []*rss.RssFeed
+	for _, f := range s.subscriptions() {
+		if f.Due() {
+			feeds = append(feeds, f)
+			copies = append(copies, &rss.RssFeed{Url: f.Url, Options: f.Options})
+		}
+	}
+	s.mu.Unlock()
+
+	errs := make([]error, len(copies))
+	var wg sync.WaitGroup
+	for i, c := range copies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = c.GetFeed()
+		}()
+	}
+	wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, f := range feeds {
+		if errs[i] != nil {
+			f.Error = errs[i].Error()
+			continue
+		}
+		f.MergeFetched(copies[i])
+	}
+	s.List.RefreshBookmarks()
+
+	s.refreshed = time.Now()
+	s.sync()
+	return s.save()
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := r.Form["api"]; !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := map[string]any{
+		"api_version":            apiVersion,
+		"auth":                   0,
+		"last_refreshed_on_time": s.refreshed.Unix(),
+	}
+
+	if r.PostForm.Get("api_key") != s.ApiKey {
+		writeJson(w, res)
+		return
+	}
+	res["auth"] = 1
+
+	if mark := r.Form.Get("mark"); mark != "" {
+		if err := s.mark(mark, r.Form.Get("as"), r.Form.Get("id"), r.Form.Get("before")); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	has := func(key string) bool {
+		_, ok := r.Form[key]
+		return ok
+	}
+
+	if has("groups") {
+		res["groups"] = s.groups()
+		res["feeds_groups"] = s.feedsGroups()
+	}
+	if has("feeds") {
+		res["feeds"] = s.feeds()
+		res["feeds_groups"] = s.feedsGroups()
+	}
+	if has("favicons") {
+		res["favicons"] = []any{}
+	}
+	if has("links") {
+		res["links"] = []any{}
+	}
+	if has("items") {
+		res["items"] = s.items(r.Form.Get("since_id"), r.Form.Get("max_id"), r.Form.Get("with_ids"))
+		res["total_items"] = len(s.entries)
+	}
+	if has("unread_item_ids") {
+		res["unread_item_ids"] = s.itemIds(func(i *rss.RssItem) bool { return !i.Read })
+	}
+	if has("saved_item_ids") {
+		res["saved_item_ids"] = s.itemIds(func(i *rss.RssItem) bool { return i.Bookmark })
+	}
+
+	writeJson(w, res)
+}
+
+func writeJson(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// subscriptions returns every feed except the bookmarks feed, which only
+// holds items that already belong to other feeds
+func (s *Server) subscriptions() []*rss.RssFeed {
+	bookmarks := s.List.Bookmarks()
+	var feeds []*rss.RssFeed
+	for _, f := range s.List.Feeds {
+		if f != bookmarks {
+			feeds = append(feeds, f)
+		}
+	}
+	return feeds
+}
+
+// sync assigns ids to anything new in the list, oldest items first, and
+// forgets the ids of items that are gone
+func (s *Server) sync() {
+	for _, category := range s.List.Categories() {
+		s.ids.group(category)
+	}
+
+	s.entries = make(map[int64]entry)
+	for _, f := range s.subscriptions() {
+		s.ids.feed(f.Url)
+		for _, item := range slices.Backward(f.RssItems) {
+			key := item.Key()
+			if key == "" {
+				continue
+			}
+			s.entries[s.ids.item(f.Url, key)] = entry{feed: f, item: item}
+		}
+	}
+
+	// Ids of items no longer in the list aren't handed out again
+	for key, id := range s.ids.Items {
+		if _, ok := s.entries[id]; !ok {
+			delete(s.ids.Items, key)
+		}
+	}
+}
+
+func (s *Server) save() error {
+	if s.IdsFile != "" {
+		if err := s.ids.save(s.IdsFile); err != nil {
+			return err
+		}
+	}
+	if s.Save != nil {
+		return s.Save()
+	}
+	return nil
+}
+
+func (s *Server) groups() []map[string]any {
+	groups := []map[string]any{}
+	for _, category := range s.List.Categories() {
+		groups = append(groups, map[string]any{
+			"id":    s.ids.group(category),
+			"title": category,
+		})
+	}
+	return groups
+}
+
+func (s *Server) feedsGroups() []map[string]any {
+	feedsGroups := []map[string]any{}
+	for _, category := range s.List.Categories() {
+		feeds, _ := s.List.GetCategory(category)
+		var feedIds []string
+		for _, f := range feeds {
+			feedIds = append(feedIds, strconv.FormatInt(s.ids.feed(f.Url), 10))
+		}
+		feedsGroups = append(feedsGroups, map[string]any{
+			"group_id": s.ids.group(category),
+			"feed_ids": strings.Join(feedIds, ","),
+		})
+	}
+	return feedsGroups
+}
+
+func (s *Server) feeds() []map[string]any {
+	feeds := []map[string]any{}
+	for _, f := range s.subscriptions() {
+		title := f.Url
+		siteUrl, _ := f.Link()
+		var updated int64
+		if f.Feed != nil {
+			if f.Feed.Title != "" {
+				title = f.Feed.Title
+			}
+			if f.Feed.UpdatedParsed != nil {
+				updated = f.Feed.UpdatedParsed.Unix()
+			}
+		}
+
+		feeds = append(feeds, map[string]any{
+			"id":                   s.ids.feed(f.Url),
+			"favicon_id":           0,
+			"title":                title,
+			"url":                  f.Url,
+			"site_url":             siteUrl,
+			"is_spark":             0,
+			"last_updated_on_time": updated,
+		})
+	}
+	return feeds
+}
+
+func (s *Server) items(sinceId, maxId, withIds string) []map[string]any {
+	var selected []int64
+
+	switch {
+	case withIds != "":
+		for _, id := range parseIds(withIds) {
+			if _, ok := s.entries[id]; ok {
+				selected = append(selected, id)
+			}
+		}
+	case maxId != "":
+		max, _ := strconv.ParseInt(maxId, 10, 64)
+		for id := range s.entries {
+			if id < max {
+				selected = append(selected, id)
+			}
+		}
+		// Paging backwards returns the newest items below max_id first
+		sort.Slice(selected, func(i, j int) bool { return selected[i] > selected[j] })
+	default:
+		since, _ := strconv.ParseInt(sinceId, 10, 64)
+		for id := range s.entries {
+			if id > since {
+				selected = append(selected, id)
+			}
+		}
+		sort.Slice(selected, func(i, j int) bool { return selected[i] < selected[j] })
+	}
+
+	if len(selected) > itemsLimit {
+		selected = selected[:itemsLimit]
+	}
+
+	items := []map[string]any{}
+	for _, id := range selected {
+		e := s.entries[id]
+		items = append(items, map[string]any{
+			"id":              id,
+			"feed_id":         s.ids.feed(e.feed.Url),
+			"title":           e.item.Item.Title,
+			"author":          author(e.item),
+			"html":            html(e.item),
+			"url":             e.item.Link(),
+			"is_saved":        boolInt(e.item.Bookmark),
+			"is_read":         boolInt(e.item.Read),
+			"created_on_time": created(e.item),
+		})
+	}
+	return items
+}
+
+func (s *Server) itemIds(match func(*rss.RssItem) bool) string {
+	var ids []int64
+	for id, e := range s.entries {
+		if match(e.item) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (s *Server) mark(kind, as, rawId, rawBefore string) error {
+	id, err := strconv.ParseInt(rawId, 10, 64)
+	if err != nil {
+		return ErrInvalidId
+	}
+
+	switch kind {
+	case "item":
+		e, ok := s.entries[id]
+		if !ok {
+			return ErrUnknownItem
+		}
+		if err := s.markItem(e.item, as); err != nil {
+			return err
+		}
+	case "feed", "group":
+		if as != "read" {
+			return ErrInvalidMark
+		}
+		before, _ := strconv.ParseInt(rawBefore, 10, 64)
+		for _, e := range s.entries {
+			if !s.inScope(kind, id, e.feed) {
+				continue
+			}
+			if before == 0 || created(e.item) < before {
+				e.item.SetRead(true)
+			}
+		}
+	default:
+		return ErrInvalidMark
+	}
+
+	return s.save()
+}
+
+func (s *Server) markItem(item *rss.RssItem, as string) error {
+	switch as {
+	case "read":
+		item.SetRead(true)
+	case "unread":
+		item.SetRead(false)
+	case "saved", "unsaved":
+		if item.Bookmark != (as == "saved") {
+			if _, err := s.List.ToggleBookmark(item); err != nil {
+				return err
+			}
+		}
+	default:
+		return ErrInvalidMark
+	}
+	return nil
+}
+
+// inScope reports whether a feed is covered by a feed or group mark.
+// Group 0 is Fever's "Kindling" super group holding every feed.
+func (s *Server) inScope(kind string, id int64, f *rss.RssFeed) bool {
+	if kind == "feed" {
+		return s.ids.feed(f.Url) == id
+	}
+	if id == 0 {
+		return true
+	}
+	for _, category := range s.List.Categories() {
+		if s.ids.group(category) != id {
+			continue
+		}
+		feeds, _ := s.List.GetCategory(category)
+		return slices.Contains(feeds, f)
+	}
+	return false
+}
+
+func parseIds(raw string) []int64 {
+	var ids []int64
+	for _, part := range strings.Split(raw, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func author(i *rss.RssItem) string {
+	if i.Item.Author != nil {
+		return i.Item.Author.Name
+	}
+	return ""
+}
+
+func html(i *rss.RssItem) string {
+	if i.Item.Content != "" {
+		return i.Item.Content
+	}
+	return i.Item.Description
+}
+
+func created(i *rss.RssItem) int64 {
+	switch {
+	case i.Date() != nil:
+		return i.Date().Unix()
+	case i.Item.UpdatedParsed != nil:
+		return i.Item.UpdatedParsed.Unix()
+	default:
+		return 0
+	}
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
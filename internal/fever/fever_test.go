@@ -0,0 +1,249 @@
+package fever
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emilosman/rssboat/internal/rss"
+	"github.com/mmcdole/gofeed"
+)
+
+func newList() *rss.List {
+	older := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	feed := &rss.RssFeed{
+		Url:      "https://example.com/feed.xml",
+		Category: "Fun",
+		Feed:     &gofeed.Feed{Title: "Feed title"},
+		RssItems: []*rss.RssItem{
+			{Item: &gofeed.Item{GUID: "2", Title: "Newer", PublishedParsed: &newer}},
+			{Item: &gofeed.Item{GUID: "1", Title: "Older", PublishedParsed: &older}, Read: true},
+		},
+	}
+
+	l := rss.NewListWithDefaults()
+	l.Add(feed)
+	l.FeedIndex[feed.Url] = feed
+	l.CategoryIndex["Fun"] = []*rss.RssFeed{feed}
+	return l
+}
+
+func request(t *testing.T, s *Server, query string, form url.Values) map[string]any {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/?api&"+query, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var res map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("invalid JSON response: %q", err)
+	}
+	return res
+}
+
+func TestFever(t *testing.T) {
+	key := ApiKey("user@example.com", "secret")
+	auth := url.Values{"api_key": {key}}
+
+	t.Run("Should reject wrong api key", func(t *testing.T) {
+		s, _ := NewServer(newList(), key, "")
+
+		res := request(t, s, "groups", url.Values{"api_key": {"wrong"}})
+		if res["auth"] != float64(0) {
+			t.Error("Should not authenticate")
+		}
+		if _, ok := res["groups"]; ok {
+			t.Error("Should not return groups when unauthenticated")
+		}
+	})
+
+	t.Run("Should list groups and feeds", func(t *testing.T) {
+		s, _ := NewServer(newList(), key, "")
+
+		res := request(t, s, "groups&feeds", auth)
+		if res["auth"] != float64(1) {
+			t.Fatal("Should authenticate")
+		}
+
+		groups := res["groups"].([]any)
+		if len(groups) != 1 || groups[0].(map[string]any)["title"] != "Fun" {
+			t.Errorf("Wrong groups returned: %v", groups)
+		}
+
+		feeds := res["feeds"].([]any)
+		if len(feeds) != 1 || feeds[0].(map[string]any)["title"] != "Feed title" {
+			t.Errorf("Bookmarks feed should not be listed: %v", feeds)
+		}
+
+		feedsGroups := res["feeds_groups"].([]any)
+		if feedsGroups[0].(map[string]any)["feed_ids"] != "1" {
+			t.Errorf("Wrong feeds_groups returned: %v", feedsGroups)
+		}
+	})
+
+	t.Run("Should assign oldest items the lowest ids", func(t *testing.T) {
+		s, _ := NewServer(newList(), key, "")
+
+		items := request(t, s, "items&since_id=0", auth)["items"].([]any)
+		if len(items) != 2 {
+			t.Fatalf("Wrong number of items, got %d", len(items))
+		}
+		if items[0].(map[string]any)["title"] != "Older" {
+			t.Error("Items should be ordered by id")
+		}
+
+		items = request(t, s, "items&since_id=1", auth)["items"].([]any)
+		if len(items) != 1 || items[0].(map[string]any)["title"] != "Newer" {
+			t.Errorf("since_id should skip older items: %v", items)
+		}
+	})
+
+	t.Run("Should mark items read and saved", func(t *testing.T) {
+		l := newList()
+		saved := 0
+		s, _ := NewServer(l, key, "")
+		s.Save = func() error {
+			saved++
+			return nil
+		}
+
+		res := request(t, s, "unread_item_ids", auth)
+		if res["unread_item_ids"] != "2" {
+			t.Errorf("Wrong unread ids: %v", res["unread_item_ids"])
+		}
+
+		request(t, s, "", url.Values{"api_key": {key}, "mark": {"item"}, "as": {"read"}, "id": {"2"}})
+		request(t, s, "", url.Values{"api_key": {key}, "mark": {"item"}, "as": {"saved"}, "id": {"2"}})
+
+		item := l.FeedIndex["https://example.com/feed.xml"].RssItems[0]
+		if !item.Read || !item.Bookmark {
+			t.Error("Item should be read and bookmarked")
+		}
+		if len(l.Bookmarks().RssItems) != 1 {
+			t.Error("Item should be added to bookmarks feed")
+		}
+		if saved != 2 {
+			t.Errorf("List should be saved after each mark, saved %d times", saved)
+		}
+
+		res = request(t, s, "saved_item_ids&unread_item_ids", auth)
+		if res["saved_item_ids"] != "2" || res["unread_item_ids"] != "" {
+			t.Errorf("Wrong item ids: %v", res)
+		}
+	})
+
+	t.Run("Should mark group read before timestamp", func(t *testing.T) {
+		l := newList()
+		l.FeedIndex["https://example.com/feed.xml"].RssItems[1].Read = false
+		s, _ := NewServer(l, key, "")
+
+		before := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC).Unix()
+		request(t, s, "", url.Values{
+			"api_key": {key},
+			"mark":    {"group"},
+			"as":      {"read"},
+			"id":      {"1"},
+			"before":  {strconv.FormatInt(before, 10)},
+		})
+
+		res := request(t, s, "unread_item_ids", auth)
+		if res["unread_item_ids"] != "2" {
+			t.Errorf("Only items before timestamp should be read: %v", res["unread_item_ids"])
+		}
+	})
+
+	t.Run("Should keep ids between runs", func(t *testing.T) {
+		idsFile := filepath.Join(t.TempDir(), "fever.json")
+
+		s, err := NewServer(newList(), key, idsFile)
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if err := s.save(); err != nil {
+			t.Fatalf("Unexpected error saving ids: %q", err)
+		}
+
+		l := newList()
+		feed := l.FeedIndex["https://example.com/feed.xml"]
+		feed.RssItems = append([]*rss.RssItem{{Item: &gofeed.Item{GUID: "3", Title: "Newest"}}}, feed.RssItems...)
+
+		s, err = NewServer(l, key, idsFile)
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		items := request(t, s, "items&since_id=2", auth)["items"].([]any)
+		if len(items) != 1 || items[0].(map[string]any)["title"] != "Newest" {
+			t.Errorf("New item should get the next id: %v", items)
+		}
+	})
+
+	t.Run("Should answer while feeds refresh", func(t *testing.T) {
+		release := make(chan struct{})
+		feeds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+			w.Write([]byte(`<?xml version="1.0"?><rss version="2.0"><channel><title>Slow</title>
+<item><title>Fetched</title><guid>fetched</guid></item></channel></rss>`))
+		}))
+		defer feeds.Close()
+
+		l := newList()
+		now := time.Now()
+		fresh := l.FeedIndex["https://example.com/feed.xml"]
+		fresh.FetchedAt, fresh.Options.Refresh = &now, time.Hour
+		l.AddFeeds(&rss.RssFeed{Url: feeds.URL, Category: "Fun"})
+		s, _ := NewServer(l, key, "")
+
+		done := make(chan error)
+		go func() { done <- s.Refresh() }()
+
+		answered := make(chan struct{})
+		go func() {
+			request(t, s, "unread_item_ids", auth)
+			close(answered)
+		}()
+		select {
+		case <-answered:
+		case <-time.After(2 * time.Second):
+			t.Fatal("Requests should be answered while feeds are fetched")
+		}
+
+		close(release)
+		if err := <-done; err != nil {
+			t.Fatalf("Unexpected error refreshing: %q", err)
+		}
+		if items := l.FeedIndex[feeds.URL].RssItems; len(items) != 1 || items[0].Item.Title != "Fetched" {
+			t.Errorf("Fetched items should be merged, got %v", items)
+		}
+	})
+
+	t.Run("Should forget ids of items that are gone", func(t *testing.T) {
+		l := newList()
+		s, _ := NewServer(l, key, "")
+
+		feed := l.FeedIndex["https://example.com/feed.xml"]
+		feed.RssItems = feed.RssItems[:1]
+		s.sync()
+
+		if len(s.ids.Items) != 1 {
+			t.Errorf("Expected the id of the remaining item only, got %v", s.ids.Items)
+		}
+		if s.ids.Next["item"] != 2 {
+			t.Error("Ids should keep growing after items are gone")
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package fever
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// Fever clients page through items with since_id and max_id, so item ids
+// must only ever grow. ids hands out sequential ids per kind and remembers
+// them between runs.
+type ids struct {
+	Groups map[string]int64
+	Feeds  map[string]int64
+	Items  map[string]int64
+	Next   map[string]int64
+}
+
+func newIds() *ids {
+	return &ids{
+		Groups: map[string]int64{},
+		Feeds:  map[string]int64{},
+		Items:  map[string]int64{},
+		Next:   map[string]int64{},
+	}
+}
+
+func (s *ids) id(kind string, index map[string]int64, key string) int64 {
+	if id, ok := index[key]; ok {
+		return id
+	}
+	s.Next[kind]++
+	index[key] = s.Next[kind]
+	return index[key]
+}
+
+func (s *ids) group(category string) int64 {
+	return s.id("group", s.Groups, category)
+}
+
+func (s *ids) feed(url string) int64 {
+	return s.id("feed", s.Feeds, url)
+}
+
+func (s *ids) item(feedUrl, key string) int64 {
+	return s.id("item", s.Items, feedUrl+"\x00"+key)
+}
+
+func loadIds(path string) (*ids, error) {
+	s := newIds()
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		return newIds(), err
+	}
+
+	return s, nil
+}
+
+func (s *ids) save(path string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,9 @@
+package fever
+
+import "errors"
+
+var (
+	ErrInvalidId   = errors.New("Invalid id")
+	ErrInvalidMark = errors.New("Invalid mark request")
+	ErrUnknownItem = errors.New("Unknown item")
+)
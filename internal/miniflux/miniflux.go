@@ -0,0 +1,217 @@
+package miniflux
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/emilosman/rssboat/internal/rss"
+	"github.com/mmcdole/gofeed"
+)
+
+// Entries fetched per feed on every sync
+const entriesLimit = 100
+
+// Backend talks to the Miniflux REST API, see https://miniflux.app/docs/api.html
+type Backend struct {
+	Url    string
+	Token  string
+	Client *http.Client
+}
+
+type category struct {
+	Id    int64  `json:"id"`
+	Title string `json:"title"`
+}
+
+type feed struct {
+	Id       int64    `json:"id"`
+	FeedUrl  string   `json:"feed_url"`
+	SiteUrl  string   `json:"site_url"`
+	Title    string   `json:"title"`
+	Category category `json:"category"`
+}
+
+type entry struct {
+	Id          int64     `json:"id"`
+	FeedId      int64     `json:"feed_id"`
+	Status      string    `json:"status"`
+	Hash        string    `json:"hash"`
+	Title       string    `json:"title"`
+	Url         string    `json:"url"`
+	PublishedAt time.Time `json:"published_at"`
+	Content     string    `json:"content"`
+	Author      string    `json:"author"`
+	Starred     bool      `json:"starred"`
+}
+
+type entriesResponse struct {
+	Total   int     `json:"total"`
+	Entries []entry `json:"entries"`
+}
+
+func New(baseUrl, token string) *Backend {
+	return &Backend{
+		Url:    strings.TrimRight(baseUrl, "/"),
+		Token:  token,
+		Client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (b *Backend) Name() string {
+	return "miniflux"
+}
+
+// Load adds every Miniflux feed to the list, categories become tabs
+func (b *Backend) Load(l *rss.List) error {
+	var feeds []feed
+	if err := b.do(http.MethodGet, "/v1/feeds", nil, &feeds); err != nil {
+		return err
+	}
+
+	for _, f := range feeds {
+		l.AddFeeds(&rss.RssFeed{
+			Url:      f.FeedUrl,
+			Category: f.Category.Title,
+			RemoteId: f.Id,
+			Feed: &gofeed.Feed{
+				Title:    f.Title,
+				Link:     f.SiteUrl,
+				FeedLink: f.FeedUrl,
+			},
+		})
+	}
+
+	return nil
+}
+
+// Push marks changed entries read or unread in one request per status,
+// and toggles the star of entries whose bookmark changed
+func (b *Backend) Push(f *rss.RssFeed) error {
+	pending := f.Pending()
+	status := map[string][]int64{}
+
+	for _, item := range pending {
+		if item.Read != item.Synced.Read {
+			s := "unread"
+			if item.Read {
+				s = "read"
+			}
+			status[s] = append(status[s], item.RemoteId)
+		}
+	}
+
+	for s, ids := range status {
+		body := map[string]any{"entry_ids": ids, "status": s}
+		if err := b.do(http.MethodPut, "/v1/entries", body, nil); err != nil {
+			return err
+		}
+	}
+
+	for _, item := range pending {
+		if item.Bookmark != item.Synced.Bookmark {
+			path := fmt.Sprintf("/v1/entries/%d/bookmark", item.RemoteId)
+			if err := b.do(http.MethodPut, path, nil, nil); err != nil {
+				return err
+			}
+		}
+		item.MarkSynced()
+	}
+
+	return nil
+}
+
+func (b *Backend) Sync(f *rss.RssFeed) error {
+	if err := b.Push(f); err != nil {
+		return err
+	}
+
+	query := url.Values{
+		"limit":     {fmt.Sprint(entriesLimit)},
+		"order":     {"published_at"},
+		"direction": {"desc"},
+	}
+	path := fmt.Sprintf("/v1/feeds/%d/entries?%s", f.RemoteId, query.Encode())
+
+	var res entriesResponse
+	if err := b.do(http.MethodGet, path, nil, &res); err != nil {
+		return err
+	}
+
+	items := make([]*rss.RssItem, 0, len(res.Entries))
+	for _, e := range res.Entries {
+		if e.Status == "removed" {
+			continue
+		}
+		items = append(items, e.toItem())
+	}
+
+	f.MergeRemote(items)
+	return nil
+}
+
+func (e entry) toItem() *rss.RssItem {
+	published := e.PublishedAt
+	item := &gofeed.Item{
+		GUID:            e.Hash,
+		Title:           e.Title,
+		Link:            e.Url,
+		Content:         e.Content,
+		PublishedParsed: &published,
+	}
+	if e.Author != "" {
+		item.Author = &gofeed.Person{Name: e.Author}
+	}
+
+	return &rss.RssItem{
+		Item:     item,
+		Read:     e.Status == "read",
+		Bookmark: e.Starred,
+		RemoteId: e.Id,
+	}
+}
+
+func (b *Backend) do(method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, b.Url+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Auth-Token", b.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := b.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		var apiErr struct {
+			ErrorMessage string `json:"error_message"`
+		}
+		json.NewDecoder(res.Body).Decode(&apiErr)
+		if apiErr.ErrorMessage != "" {
+			return fmt.Errorf("miniflux: %s", apiErr.ErrorMessage)
+		}
+		return fmt.Errorf("miniflux: %s %s: %s", method, path, res.Status)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
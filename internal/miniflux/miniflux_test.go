@@ -0,0 +1,152 @@
+package miniflux
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/emilosman/rssboat/internal/rss"
+)
+
+type fakeMiniflux struct {
+	mu      sync.Mutex
+	status  map[int64]string
+	starred map[int64]bool
+}
+
+func newFakeMiniflux(t *testing.T) (*fakeMiniflux, *httptest.Server) {
+	t.Helper()
+
+	fake := &fakeMiniflux{
+		status:  map[int64]string{1: "unread", 2: "read"},
+		starred: map[int64]bool{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/feeds", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]feed{{
+			Id:       7,
+			FeedUrl:  "https://example.com/feed.xml",
+			SiteUrl:  "https://example.com",
+			Title:    "Example",
+			Category: category{Id: 1, Title: "Tech"},
+		}})
+	})
+	mux.HandleFunc("GET /v1/feeds/7/entries", func(w http.ResponseWriter, r *http.Request) {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		json.NewEncoder(w).Encode(entriesResponse{Total: 2, Entries: []entry{
+			{Id: 1, FeedId: 7, Hash: "a", Title: "First", Status: fake.status[1], Starred: fake.starred[1]},
+			{Id: 2, FeedId: 7, Hash: "b", Title: "Second", Status: fake.status[2], Starred: fake.starred[2]},
+		}})
+	})
+	mux.HandleFunc("PUT /v1/entries", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			EntryIds []int64 `json:"entry_ids"`
+			Status   string  `json:"status"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		for _, id := range body.EntryIds {
+			fake.status[id] = body.Status
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("PUT /v1/entries/1/bookmark", func(w http.ResponseWriter, r *http.Request) {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		fake.starred[1] = !fake.starred[1]
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Auth-Token") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error_message": "Access Unauthorized"})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return fake, server
+}
+
+func TestMiniflux(t *testing.T) {
+	t.Run("Should load feeds with categories as tabs", func(t *testing.T) {
+		_, server := newFakeMiniflux(t)
+
+		l := rss.NewListWithDefaults()
+		err := New(server.URL, "token").Load(l)
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		feeds, _ := l.GetCategory("Tech")
+		if len(feeds) != 1 || feeds[0].RemoteId != 7 {
+			t.Errorf("Feed not loaded into category: %v", feeds)
+		}
+		if feeds[0].Title() != "Example" {
+			t.Errorf("Wrong feed title, got %s", feeds[0].Title())
+		}
+	})
+
+	t.Run("Should report API errors", func(t *testing.T) {
+		_, server := newFakeMiniflux(t)
+
+		err := New(server.URL, "wrong").Load(rss.NewListWithDefaults())
+		if err == nil || err.Error() != "miniflux: Access Unauthorized" {
+			t.Errorf("Wrong error, got %v", err)
+		}
+	})
+
+	t.Run("Should pull entries with remote state", func(t *testing.T) {
+		_, server := newFakeMiniflux(t)
+		b := New(server.URL, "token")
+		f := &rss.RssFeed{Url: "https://example.com/feed.xml", RemoteId: 7, Backend: b}
+
+		if err := f.GetFeed(); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		if len(f.RssItems) != 2 {
+			t.Fatalf("Wrong number of items, got %d", len(f.RssItems))
+		}
+		for _, item := range f.RssItems {
+			if item.Read != (item.RemoteId == 2) {
+				t.Errorf("Wrong read state for entry %d", item.RemoteId)
+			}
+		}
+	})
+
+	t.Run("Should push read and bookmark changes", func(t *testing.T) {
+		fake, server := newFakeMiniflux(t)
+		b := New(server.URL, "token")
+		f := &rss.RssFeed{Url: "https://example.com/feed.xml", RemoteId: 7, Backend: b}
+		f.GetFeed()
+
+		for _, item := range f.RssItems {
+			item.ToggleRead()
+			if item.RemoteId == 1 {
+				item.ToggleBookmark()
+			}
+		}
+
+		if err := b.Push(f); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		if fake.status[1] != "read" || fake.status[2] != "unread" {
+			t.Errorf("Status not pushed: %v", fake.status)
+		}
+		if !fake.starred[1] {
+			t.Error("Bookmark not pushed")
+		}
+		if len(f.Pending()) != 0 {
+			t.Error("Items should be synced after push")
+		}
+	})
+}
@@ -0,0 +1,9 @@
+package newsboat
+
+import "errors"
+
+var (
+	ErrNotSqlite    = errors.New("Not a Newsboat cache.db file")
+	ErrMissingTable = errors.New("Table missing from Newsboat cache")
+	ErrCacheInUse   = errors.New("Newsboat cache has unsaved changes, close Newsboat first")
+)
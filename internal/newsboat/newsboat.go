@@ -0,0 +1,243 @@
+package newsboat
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/emilosman/rssboat/internal/rss"
+	"github.com/mmcdole/gofeed"
+)
+
+// Feeds without tags go to this category
+const untagged = "Uncategorized"
+
+// Item is an item from Newsboat's cache
+type Item struct {
+	Guid    string
+	FeedUrl string
+	Title   string
+	Url     string
+	Author  string
+	Content string
+	PubDate time.Time
+	Unread  bool
+	Flagged bool
+	Deleted bool
+}
+
+// ParseUrls reads a Newsboat urls file. Tags become categories, a feed with
+// several tags is listed in each, "~Title" sets the title and "!" hides the
+// feed. Query, exec and filter feeds can't be fetched by rssboat and are
+// returned as skipped.
+func ParseUrls(r io.Reader) (feeds []*rss.RssFeed, skipped []string, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := splitFields(line)
+		url := fields[0]
+		if strings.HasPrefix(url, "query:") || strings.HasPrefix(url, "exec:") || strings.HasPrefix(url, "filter:") {
+			skipped = append(skipped, url)
+			continue
+		}
+
+		var (
+			options rss.FeedOptions
+			tags    []string
+		)
+		for _, field := range fields[1:] {
+			switch {
+			case field == "!":
+				options.Hidden = true
+			case strings.HasPrefix(field, "~"):
+				options.Title = field[1:]
+			case field != "":
+				tags = append(tags, field)
+			}
+		}
+		if len(tags) == 0 {
+			tags = []string{untagged}
+		}
+
+		for _, tag := range tags {
+			feeds = append(feeds, &rss.RssFeed{Url: url, Category: tag, Options: options})
+		}
+	}
+
+	return feeds, skipped, scanner.Err()
+}
+
+// splitFields splits a urls line on spaces, keeping "quoted tags" together
+func splitFields(line string) []string {
+	var (
+		fields []string
+		field  strings.Builder
+		quoted bool
+	)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\' && quoted && i+1 < len(line):
+			i++
+			field.WriteByte(line[i])
+		case c == '"':
+			quoted = !quoted
+		case (c == ' ' || c == '\t') && !quoted:
+			if field.Len() > 0 {
+				fields = append(fields, field.String())
+				field.Reset()
+			}
+		case c == '#' && !quoted && field.Len() == 0:
+			// A comment at the end of the line
+			return fields
+		default:
+			field.WriteByte(c)
+		}
+	}
+	if field.Len() > 0 {
+		fields = append(fields, field.String())
+	}
+	return fields
+}
+
+// ReadCache reads the items in Newsboat's cache.db
+func ReadCache(path string) ([]Item, error) {
+	db, f, err := openSqlite(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var items []Item
+	err = db.rows("rss_item", func(row map[string]any) error {
+		item := Item{
+			Guid:    text(row["guid"]),
+			FeedUrl: text(row["feedurl"]),
+			Title:   text(row["title"]),
+			Url:     text(row["url"]),
+			Author:  text(row["author"]),
+			Content: text(row["content"]),
+			Unread:  number(row["unread"]) != 0,
+			Flagged: text(row["flags"]) != "",
+			Deleted: number(row["deleted"]) != 0,
+		}
+		if pubDate := number(row["pubDate"]); pubDate > 0 {
+			item.PubDate = time.Unix(pubDate, 0)
+		}
+		items = append(items, item)
+		return nil
+	})
+
+	return items, err
+}
+
+func text(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
+
+func number(v any) int64 {
+	switch v := v.(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
+
+// ApplyCache carries read and flagged state over to the list's items, matched
+// by GUID. Items rssboat hasn't fetched yet are added, so their state isn't
+// lost when the feed no longer lists them. Deleted items are skipped.
+func ApplyCache(l *rss.List, items []Item) (matched, added int) {
+	keys := make(map[string]map[string]*rss.RssItem)
+	for _, f := range l.Feeds {
+		keys[f.Url] = make(map[string]*rss.RssItem, len(f.RssItems))
+		for _, i := range f.RssItems {
+			keys[f.Url][i.Key()] = i
+		}
+	}
+
+	changed := make(map[*rss.RssFeed]bool)
+	for _, item := range items {
+		feed := l.FeedIndex[item.FeedUrl]
+		if feed == nil || item.Deleted || item.Guid == "" || feed == l.Bookmarks() {
+			continue
+		}
+
+		existing, ok := keys[feed.Url][item.Guid]
+		if ok {
+			matched++
+		} else {
+			existing = newItem(item)
+			feed.RssItems = append(feed.RssItems, existing)
+			keys[feed.Url][item.Guid] = existing
+			changed[feed] = true
+			added++
+		}
+
+		existing.SetRead(!item.Unread)
+		existing.SetBookmark(item.Flagged)
+	}
+
+	for feed := range changed {
+		feed.SortByDate()
+	}
+	l.RefreshBookmarks()
+
+	return matched, added
+}
+
+func newItem(item Item) *rss.RssItem {
+	i := &rss.RssItem{
+		Item: &gofeed.Item{
+			GUID:    item.Guid,
+			Title:   item.Title,
+			Link:    item.Url,
+			Content: item.Content,
+		},
+	}
+	if item.Author != "" {
+		i.Item.Author = &gofeed.Person{Name: item.Author}
+	}
+	if !item.PubDate.IsZero() {
+		i.Item.PublishedParsed = &item.PubDate
+		i.FirstSeen = &item.PubDate
+	}
+	return i
+}
+
+// DefaultPaths returns where Newsboat keeps its urls file and cache, in
+// ~/.newsboat or the XDG directories newer versions use
+func DefaultPaths() (urls, cache string) {
+	home, _ := os.UserHomeDir()
+
+	dotDir := filepath.Join(home, ".newsboat")
+	if _, err := os.Stat(dotDir); err == nil {
+		return filepath.Join(dotDir, "urls"), filepath.Join(dotDir, "cache.db")
+	}
+
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(home, ".config")
+	}
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(configHome, "newsboat", "urls"), filepath.Join(dataHome, "newsboat", "cache.db")
+}
@@ -0,0 +1,142 @@
+package newsboat
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/emilosman/rssboat/internal/rss"
+	"github.com/mmcdole/gofeed"
+)
+
+func TestNewsboat(t *testing.T) {
+	t.Run("Should map tags to categories and keep titles", func(t *testing.T) {
+		urls := `# my feeds
+https://go.dev/blog/feed.atom go "~The Go Blog"
+https://example.com/both.xml tech "news & views"  # comment
+https://example.com/hidden.xml ! secret
+https://example.com/untagged.xml
+"query:Unread:unread = \"yes\""
+exec:~/bin/feed.sh tech
+`
+		feeds, skipped, err := ParseUrls(strings.NewReader(urls))
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		var got []string
+		for _, f := range feeds {
+			got = append(got, f.Category+" "+f.Url)
+		}
+		want := []string{
+			"go https://go.dev/blog/feed.atom",
+			"tech https://example.com/both.xml",
+			"news & views https://example.com/both.xml",
+			"secret https://example.com/hidden.xml",
+			"Uncategorized https://example.com/untagged.xml",
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+
+		if feeds[0].Options.Title != "The Go Blog" {
+			t.Errorf("Title not kept, got %q", feeds[0].Options.Title)
+		}
+		if !feeds[3].Options.Hidden {
+			t.Error("! should hide the feed")
+		}
+		if len(skipped) != 2 {
+			t.Errorf("Query and exec feeds should be skipped, got %v", skipped)
+		}
+	})
+
+	t.Run("Should read items from cache.db", func(t *testing.T) {
+		items, err := ReadCache(filepath.Join("testdata", "cache.db"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		if len(items) != 63 {
+			t.Fatalf("Wrong number of items, got %d", len(items))
+		}
+
+		first := items[0]
+		if first.Guid != "guid-0" || first.Unread || !first.Flagged || first.Deleted {
+			t.Errorf("Wrong first item: %+v", first)
+		}
+		if first.PubDate.Unix() != 1700000000 {
+			t.Errorf("Wrong date, got %v", first.PubDate)
+		}
+		if !items[1].Unread || items[1].Flagged {
+			t.Errorf("Wrong second item: %+v", items[1])
+		}
+
+		long := items[60]
+		if long.Guid != "guid-long" || len(long.Content) != 5003 || !strings.HasSuffix(long.Content, "end") {
+			t.Errorf("Content spanning overflow pages not read, got %d bytes", len(long.Content))
+		}
+		if !items[61].Deleted {
+			t.Error("Deleted item not marked")
+		}
+	})
+
+	t.Run("Should reject files that aren't SQLite", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cache.db")
+		os.WriteFile(path, []byte("not a database"), 0644)
+
+		_, err := ReadCache(path)
+		if !errors.Is(err, ErrNotSqlite) {
+			t.Errorf("Expected ErrNotSqlite, got %v", err)
+		}
+	})
+
+	t.Run("Should report truncated and corrupt files instead of failing", func(t *testing.T) {
+		data, err := os.ReadFile(filepath.Join("testdata", "cache.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(t.TempDir(), "cache.db")
+
+		for _, size := range []int{100, 1000, 4096, 8192, len(data) - 1} {
+			os.WriteFile(path, data[:size], 0644)
+			if _, err := ReadCache(path); !errors.Is(err, ErrNotSqlite) {
+				t.Errorf("Expected ErrNotSqlite for %d bytes, got %v", size, err)
+			}
+		}
+
+		// Damaged bytes anywhere must not panic or loop, errors are fine
+		for i := 100; i < len(data); i += 7 {
+			damaged := slices.Clone(data)
+			damaged[i] ^= 0xff
+			os.WriteFile(path, damaged, 0644)
+			ReadCache(path)
+		}
+	})
+
+	t.Run("Should carry read and flagged state over by GUID", func(t *testing.T) {
+		fetched := &rss.RssItem{Item: &gofeed.Item{GUID: "guid-1"}}
+		l := rss.NewListWithDefaults()
+		l.AddFeeds(&rss.RssFeed{Url: "https://example.com/feed.xml", Category: "tech", RssItems: []*rss.RssItem{fetched}})
+
+		items := []Item{
+			{Guid: "guid-1", FeedUrl: "https://example.com/feed.xml", Flagged: true},
+			{Guid: "guid-2", FeedUrl: "https://example.com/feed.xml", Unread: true},
+			{Guid: "guid-3", FeedUrl: "https://example.com/feed.xml", Deleted: true},
+			{Guid: "guid-4", FeedUrl: "https://unknown.com/feed.xml"},
+		}
+
+		matched, added := ApplyCache(l, items)
+		if matched != 1 || added != 1 {
+			t.Errorf("got %d matched and %d added", matched, added)
+		}
+		if !fetched.Read || !fetched.Bookmark {
+			t.Error("State not carried over")
+		}
+		if len(l.Bookmarks().RssItems) != 1 {
+			t.Error("Flagged item should be bookmarked")
+		}
+	})
+}
@@ -0,0 +1,376 @@
+package newsboat
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// sqlite reads rows of tables from an SQLite database file. It only supports
+// what reading Newsboat's cache needs: UTF-8 databases without a pending WAL,
+// scanned table by table.
+type sqlite struct {
+	f        io.ReaderAt
+	pageSize int
+	usable   int
+	// pages in the file, page numbers past it are corrupt
+	pages uint32
+}
+
+const (
+	sqliteMagic       = "SQLite format 3\x00"
+	interiorTablePage = 0x05
+	leafTablePage     = 0x0d
+)
+
+func openSqlite(path string) (*sqlite, *os.File, error) {
+	if info, err := os.Stat(path + "-wal"); err == nil && info.Size() > 0 {
+		return nil, nil, ErrCacheInUse
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := make([]byte, 100)
+	if _, err := f.ReadAt(header, 0); err != nil || string(header[:16]) != sqliteMagic {
+		f.Close()
+		return nil, nil, ErrNotSqlite
+	}
+
+	pageSize := int(binary.BigEndian.Uint16(header[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	usable := pageSize - int(header[20])
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 || usable < 480 {
+		f.Close()
+		return nil, nil, corrupt("page size %d", pageSize)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	if encoding := binary.BigEndian.Uint32(header[56:60]); encoding > 1 {
+		f.Close()
+		return nil, nil, fmt.Errorf("%w: text is not UTF-8", ErrNotSqlite)
+	}
+
+	db := &sqlite{
+		f:        f,
+		pageSize: pageSize,
+		usable:   usable,
+		pages:    uint32(info.Size() / int64(pageSize)),
+	}
+	return db, f, nil
+}
+
+// corrupt reports a file that claims to be SQLite but doesn't read as one,
+// such as one cut short
+func corrupt(format string, args ...any) error {
+	return fmt.Errorf("%w: corrupt file, %s", ErrNotSqlite, fmt.Sprintf(format, args...))
+}
+
+func (db *sqlite) page(n uint32) ([]byte, error) {
+	if n == 0 || n > db.pages {
+		return nil, corrupt("page %d of %d", n, db.pages)
+	}
+	page := make([]byte, db.pageSize)
+	if _, err := db.f.ReadAt(page, int64(n-1)*int64(db.pageSize)); err != nil {
+		return nil, corrupt("page %d: %v", n, err)
+	}
+	return page, nil
+}
+
+// rows calls fn with each row of a table, columns named as in its schema
+func (db *sqlite) rows(table string, fn func(row map[string]any) error) error {
+	var (
+		root    uint32
+		columns []string
+	)
+
+	err := db.scan(1, func(rowid int64, values []any) error {
+		if len(values) < 5 || values[0] != "table" || values[1] != table {
+			return nil
+		}
+		rootPage, _ := values[3].(int64)
+		sql, _ := values[4].(string)
+		root, columns = uint32(rootPage), columnNames(sql)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if root == 0 {
+		return fmt.Errorf("%w: %s", ErrMissingTable, table)
+	}
+
+	return db.scan(root, func(rowid int64, values []any) error {
+		row := make(map[string]any, len(columns))
+		for i, column := range columns {
+			var value any
+			if i < len(values) {
+				value = values[i]
+			}
+			// An INTEGER PRIMARY KEY is stored as the rowid
+			if value == nil && i == 0 {
+				value = rowid
+			}
+			row[column] = value
+		}
+		return fn(row)
+	})
+}
+
+// scan walks a table b-tree in rowid order
+func (db *sqlite) scan(n uint32, fn func(rowid int64, values []any) error) error {
+	return db.walk(n, map[uint32]bool{}, fn)
+}
+
+// walk is scan, with the pages visited so far so a corrupt tree that points
+// back up doesn't loop
+func (db *sqlite) walk(n uint32, visited map[uint32]bool, fn func(rowid int64, values []any) error) error {
+	if visited[n] {
+		return corrupt("page %d is linked twice", n)
+	}
+	visited[n] = true
+
+	page, err := db.page(n)
+	if err != nil {
+		return err
+	}
+
+	// Page 1 starts with the database header
+	offset := 0
+	if n == 1 {
+		offset = 100
+	}
+
+	kind := page[offset]
+	headerSize := 8
+	if kind == interiorTablePage {
+		headerSize = 12
+	}
+	cells := int(binary.BigEndian.Uint16(page[offset+3:]))
+	if offset+headerSize+2*cells > db.usable {
+		return corrupt("%d cells on page %d", cells, n)
+	}
+
+	for i := range cells {
+		ptr := int(binary.BigEndian.Uint16(page[offset+headerSize+2*i:]))
+		if ptr < offset+headerSize || ptr >= db.usable {
+			return corrupt("cell at %d on page %d", ptr, n)
+		}
+
+		switch kind {
+		case interiorTablePage:
+			if ptr+4 > db.usable {
+				return corrupt("cell at %d on page %d", ptr, n)
+			}
+			child := binary.BigEndian.Uint32(page[ptr:])
+			if err := db.walk(child, visited, fn); err != nil {
+				return err
+			}
+		case leafTablePage:
+			size, k := varint(page[ptr:db.usable])
+			rowid, l := varint(page[ptr+k : db.usable])
+			if k == 0 || l == 0 || size > math.MaxInt32 {
+				return corrupt("cell at %d on page %d", ptr, n)
+			}
+			payload, err := db.payload(page, ptr+k+l, int(size), visited)
+			if err != nil {
+				return err
+			}
+			values, err := record(payload)
+			if err != nil {
+				return err
+			}
+			if err := fn(int64(rowid), values); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%w: unexpected page type %d", ErrNotSqlite, kind)
+		}
+	}
+
+	if kind == interiorTablePage {
+		return db.walk(binary.BigEndian.Uint32(page[offset+8:]), visited, fn)
+	}
+	return nil
+}
+
+// payload reads a cell's payload, following overflow pages when it doesn't fit
+func (db *sqlite) payload(page []byte, start, size int, visited map[uint32]bool) ([]byte, error) {
+	maxLocal := db.usable - 35
+	if size <= maxLocal {
+		if start+size > db.usable {
+			return nil, corrupt("payload of %d bytes at %d", size, start)
+		}
+		return page[start : start+size], nil
+	}
+
+	minLocal := (db.usable-12)*32/255 - 23
+	local := minLocal + (size-minLocal)%(db.usable-4)
+	if local > maxLocal {
+		local = minLocal
+	}
+	if start+local+4 > db.usable {
+		return nil, corrupt("payload of %d bytes at %d", size, start)
+	}
+
+	payload := append([]byte{}, page[start:start+local]...)
+	next := binary.BigEndian.Uint32(page[start+local:])
+	for next != 0 && len(payload) < size {
+		if visited[next] {
+			return nil, corrupt("page %d is linked twice", next)
+		}
+		visited[next] = true
+
+		overflow, err := db.page(next)
+		if err != nil {
+			return nil, err
+		}
+		next = binary.BigEndian.Uint32(overflow)
+		payload = append(payload, overflow[4:min(db.usable, 4+size-len(payload))]...)
+	}
+	if len(payload) < size {
+		return nil, corrupt("payload of %d bytes at %d", size, start)
+	}
+
+	return payload, nil
+}
+
+// record decodes the values of a row
+func record(payload []byte) ([]any, error) {
+	headerSize, n := varint(payload)
+	if n == 0 || headerSize > uint64(len(payload)) {
+		return nil, fmt.Errorf("%w: corrupt record", ErrNotSqlite)
+	}
+
+	var types []uint64
+	for pos := n; pos < int(headerSize); {
+		t, k := varint(payload[pos:headerSize])
+		if k == 0 {
+			return nil, fmt.Errorf("%w: corrupt record", ErrNotSqlite)
+		}
+		types = append(types, t)
+		pos += k
+	}
+
+	values := make([]any, len(types))
+	body := payload[headerSize:]
+	for i, t := range types {
+		var size int
+		switch {
+		case t == 0, t == 8, t == 9:
+			size = 0
+		case t <= 4:
+			size = int(t)
+		case t == 5:
+			size = 6
+		case t == 6, t == 7:
+			size = 8
+		case t >= 12:
+			size = int(t-12) / 2
+		default:
+			return nil, fmt.Errorf("%w: unknown serial type %d", ErrNotSqlite, t)
+		}
+		if size < 0 || size > len(body) {
+			return nil, fmt.Errorf("%w: corrupt record", ErrNotSqlite)
+		}
+
+		data := body[:size]
+		body = body[size:]
+
+		switch {
+		case t == 0:
+			values[i] = nil
+		case t == 8:
+			values[i] = int64(0)
+		case t == 9:
+			values[i] = int64(1)
+		case t == 7:
+			values[i] = math.Float64frombits(binary.BigEndian.Uint64(data))
+		case t <= 6:
+			var v int64
+			for _, b := range data {
+				v = v<<8 | int64(b)
+			}
+			// Sign extend from the stored width
+			shift := 64 - 8*size
+			values[i] = v << shift >> shift
+		case t%2 == 0:
+			values[i] = data
+		default:
+			values[i] = string(data)
+		}
+	}
+
+	return values, nil
+}
+
+// varint decodes SQLite's big-endian variable length integer
+func varint(b []byte) (uint64, int) {
+	var v uint64
+	for i := range 8 {
+		if i >= len(b) {
+			return v, i
+		}
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	if len(b) < 9 {
+		return v, len(b)
+	}
+	return v<<8 | uint64(b[8]), 9
+}
+
+// columnNames reads the column names from a CREATE TABLE statement
+func columnNames(sql string) []string {
+	start, end := strings.Index(sql, "("), strings.LastIndex(sql, ")")
+	if start == -1 || end < start {
+		return nil
+	}
+
+	var (
+		names []string
+		depth int
+		part  strings.Builder
+	)
+	definitions := []string{}
+	for _, r := range sql[start+1 : end] {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			definitions = append(definitions, part.String())
+			part.Reset()
+			continue
+		}
+		part.WriteRune(r)
+	}
+	definitions = append(definitions, part.String())
+
+	for _, definition := range definitions {
+		fields := strings.Fields(definition)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "PRIMARY", "UNIQUE", "CHECK", "FOREIGN", "CONSTRAINT":
+			continue
+		}
+		names = append(names, strings.Trim(fields[0], "\"`[]"))
+	}
+
+	return names
+}
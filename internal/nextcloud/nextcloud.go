@@ -0,0 +1,253 @@
+package nextcloud
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/emilosman/rssboat/internal/rss"
+	"github.com/mmcdole/gofeed"
+)
+
+const (
+	apiPath = "/index.php/apps/news/api/v1-3"
+	// Tab for feeds that are not in a folder
+	rootFolder = "Uncategorized"
+)
+
+// Backend talks to the Nextcloud News v1.3 API, see
+// https://nextcloud.github.io/news/api/api-v1-3/
+type Backend struct {
+	Url      string
+	User     string
+	Password string
+	Client   *http.Client
+}
+
+type folder struct {
+	Id   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type feed struct {
+	Id       int64  `json:"id"`
+	Url      string `json:"url"`
+	Title    string `json:"title"`
+	Link     string `json:"link"`
+	FolderId int64  `json:"folderId"`
+}
+
+type item struct {
+	Id            int64  `json:"id"`
+	Guid          string `json:"guid"`
+	GuidHash      string `json:"guidHash"`
+	Url           string `json:"url"`
+	Title         string `json:"title"`
+	Author        string `json:"author"`
+	PubDate       int64  `json:"pubDate"`
+	Body          string `json:"body"`
+	EnclosureLink string `json:"enclosureLink"`
+	FeedId        int64  `json:"feedId"`
+	Unread        bool   `json:"unread"`
+	Starred       bool   `json:"starred"`
+	LastModified  int64  `json:"lastModified"`
+}
+
+func New(baseUrl, user, password string) *Backend {
+	return &Backend{
+		Url:      strings.TrimRight(baseUrl, "/"),
+		User:     user,
+		Password: password,
+		Client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (b *Backend) Name() string {
+	return "nextcloud"
+}
+
+// Load adds every Nextcloud News feed to the list, folders become tabs
+func (b *Backend) Load(l *rss.List) error {
+	var folders struct {
+		Folders []folder `json:"folders"`
+	}
+	if err := b.do(http.MethodGet, "/folders", nil, &folders); err != nil {
+		return err
+	}
+
+	names := map[int64]string{}
+	for _, f := range folders.Folders {
+		names[f.Id] = f.Name
+	}
+
+	var feeds struct {
+		Feeds []feed `json:"feeds"`
+	}
+	if err := b.do(http.MethodGet, "/feeds", nil, &feeds); err != nil {
+		return err
+	}
+
+	for _, f := range feeds.Feeds {
+		category, ok := names[f.FolderId]
+		if !ok {
+			category = rootFolder
+		}
+
+		l.AddFeeds(&rss.RssFeed{
+			Url:      f.Url,
+			Category: category,
+			RemoteId: f.Id,
+			Feed: &gofeed.Feed{
+				Title:    f.Title,
+				Link:     f.Link,
+				FeedLink: f.Url,
+			},
+		})
+	}
+
+	return nil
+}
+
+// Push sends pending changes in at most one batch per kind of change.
+// Items stay pending when a batch fails, so they are retried on the next sync.
+func (b *Backend) Push(f *rss.RssFeed) error {
+	pending := f.Pending()
+	batches := map[string][]int64{}
+
+	for _, i := range pending {
+		if i.Read != i.Synced.Read {
+			kind := "unread"
+			if i.Read {
+				kind = "read"
+			}
+			batches[kind] = append(batches[kind], i.RemoteId)
+		}
+		if i.Bookmark != i.Synced.Bookmark {
+			kind := "unstar"
+			if i.Bookmark {
+				kind = "star"
+			}
+			batches[kind] = append(batches[kind], i.RemoteId)
+		}
+	}
+
+	for kind, ids := range batches {
+		path := fmt.Sprintf("/items/%s/multiple", kind)
+		if err := b.do(http.MethodPost, path, map[string]any{"itemIds": ids}, nil); err != nil {
+			return err
+		}
+	}
+
+	for _, i := range pending {
+		i.MarkSynced()
+	}
+
+	return nil
+}
+
+// Sync pushes pending changes, then pulls the whole feed the first time and
+// only items modified since the last sync afterwards
+func (b *Backend) Sync(f *rss.RssFeed) error {
+	if err := b.Push(f); err != nil {
+		return err
+	}
+
+	query := url.Values{
+		"type": {"0"},
+		"id":   {fmt.Sprint(f.RemoteId)},
+	}
+
+	path := "/items"
+	if f.LastModified == 0 {
+		query.Set("batchSize", "-1")
+		query.Set("getRead", "true")
+	} else {
+		path = "/items/updated"
+		query.Set("lastModified", fmt.Sprint(f.LastModified))
+	}
+
+	var res struct {
+		Items []item `json:"items"`
+	}
+	if err := b.do(http.MethodGet, path+"?"+query.Encode(), nil, &res); err != nil {
+		return err
+	}
+
+	items := make([]*rss.RssItem, 0, len(res.Items))
+	for _, i := range res.Items {
+		items = append(items, i.toItem())
+		f.LastModified = max(f.LastModified, i.LastModified)
+	}
+
+	f.MergeRemote(items)
+	return nil
+}
+
+func (i item) toItem() *rss.RssItem {
+	published := time.Unix(i.PubDate, 0)
+	gi := &gofeed.Item{
+		GUID:            i.GuidHash,
+		Title:           i.Title,
+		Link:            i.Url,
+		Content:         i.Body,
+		PublishedParsed: &published,
+	}
+	if i.Author != "" {
+		gi.Author = &gofeed.Person{Name: i.Author}
+	}
+	if i.EnclosureLink != "" {
+		gi.Enclosures = []*gofeed.Enclosure{{URL: i.EnclosureLink}}
+	}
+
+	return &rss.RssItem{
+		Item:     gi,
+		Read:     !i.Unread,
+		Bookmark: i.Starred,
+		RemoteId: i.Id,
+	}
+}
+
+func (b *Backend) do(method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, b.Url+apiPath+path, reader)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(b.User, b.Password)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := b.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(res.Body).Decode(&apiErr)
+		if apiErr.Message != "" {
+			return fmt.Errorf("nextcloud: %s", apiErr.Message)
+		}
+		return fmt.Errorf("nextcloud: %s %s: %s", method, path, res.Status)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
@@ -0,0 +1,203 @@
+package nextcloud
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/emilosman/rssboat/internal/rss"
+)
+
+type fakeNews struct {
+	mu      sync.Mutex
+	items   []item
+	batches map[string][]int64
+	queries []string
+}
+
+func newFakeNews(t *testing.T) (*fakeNews, *httptest.Server) {
+	t.Helper()
+
+	fake := &fakeNews{
+		items: []item{
+			{Id: 1, GuidHash: "a", Title: "First", FeedId: 3, Unread: true, LastModified: 100},
+			{Id: 2, GuidHash: "b", Title: "Second", FeedId: 3, Unread: false, LastModified: 200},
+		},
+		batches: map[string][]int64{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+apiPath+"/folders", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"folders": []folder{{Id: 4, Name: "Media"}}})
+	})
+	mux.HandleFunc("GET "+apiPath+"/feeds", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"feeds": []feed{
+			{Id: 3, Url: "https://example.com/feed.xml", Title: "Example", FolderId: 4},
+			{Id: 5, Url: "https://example.com/root.xml", Title: "Root"},
+		}})
+	})
+	items := func(w http.ResponseWriter, r *http.Request) {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		fake.queries = append(fake.queries, r.URL.Path+"?"+r.URL.RawQuery)
+		json.NewEncoder(w).Encode(map[string]any{"items": fake.items})
+	}
+	mux.HandleFunc("GET "+apiPath+"/items", items)
+	mux.HandleFunc("GET "+apiPath+"/items/updated", items)
+	mux.HandleFunc("POST "+apiPath+"/items/{kind}/multiple", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			ItemIds []int64 `json:"itemIds"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		fake.batches[r.PathValue("kind")] = append(fake.batches[r.PathValue("kind")], body.ItemIds...)
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, _ := r.BasicAuth(); user != "user" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Unauthorized"})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return fake, server
+}
+
+func TestNextcloud(t *testing.T) {
+	t.Run("Should load feeds with folders as tabs", func(t *testing.T) {
+		_, server := newFakeNews(t)
+
+		l := rss.NewListWithDefaults()
+		if err := New(server.URL, "user", "secret").Load(l); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		if feeds, _ := l.GetCategory("Media"); len(feeds) != 1 {
+			t.Error("Feed in folder not loaded into tab")
+		}
+		if feeds, _ := l.GetCategory(rootFolder); len(feeds) != 1 {
+			t.Error("Feed without folder not loaded into root tab")
+		}
+	})
+
+	t.Run("Should report API errors", func(t *testing.T) {
+		_, server := newFakeNews(t)
+
+		err := New(server.URL, "user", "wrong").Load(rss.NewListWithDefaults())
+		if err == nil || err.Error() != "nextcloud: Unauthorized" {
+			t.Errorf("Wrong error, got %v", err)
+		}
+	})
+
+	t.Run("Should sync incrementally after the first sync", func(t *testing.T) {
+		fake, server := newFakeNews(t)
+		f := &rss.RssFeed{Url: "https://example.com/feed.xml", RemoteId: 3, Backend: New(server.URL, "user", "secret")}
+
+		if err := f.GetFeed(); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if f.LastModified != 200 {
+			t.Errorf("Wrong sync cursor, got %d", f.LastModified)
+		}
+
+		fake.items = []item{{Id: 1, GuidHash: "a", Title: "First", FeedId: 3, Unread: false, Starred: true, LastModified: 300}}
+		if err := f.GetFeed(); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		if !strings.Contains(fake.queries[1], "/items/updated") || !strings.Contains(fake.queries[1], "lastModified=200") {
+			t.Errorf("Second sync should be incremental, got %s", fake.queries[1])
+		}
+		if len(f.RssItems) != 2 {
+			t.Errorf("Incremental sync should keep unchanged items, got %d", len(f.RssItems))
+		}
+		for _, i := range f.RssItems {
+			if i.RemoteId == 1 && (!i.Read || !i.Bookmark) {
+				t.Error("Remote changes not applied")
+			}
+		}
+	})
+
+	t.Run("Should push changes in batches", func(t *testing.T) {
+		fake, server := newFakeNews(t)
+		b := New(server.URL, "user", "secret")
+		f := &rss.RssFeed{Url: "https://example.com/feed.xml", RemoteId: 3, Backend: b}
+		f.GetFeed()
+
+		for _, i := range f.RssItems {
+			i.ToggleRead()
+			i.ToggleBookmark()
+		}
+
+		if err := b.Push(f); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		if len(fake.batches["read"]) != 1 || len(fake.batches["unread"]) != 1 || len(fake.batches["star"]) != 2 {
+			t.Errorf("Wrong batches sent: %v", fake.batches)
+		}
+	})
+
+	t.Run("Should work offline with queued changes", func(t *testing.T) {
+		t.Setenv("XDG_CACHE_HOME", t.TempDir())
+		_, server := newFakeNews(t)
+		b := New(server.URL, "user", "secret")
+
+		l, err := rss.LoadRemoteList(b)
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		feed := l.FeedIndex["https://example.com/feed.xml"]
+		if err := feed.GetFeed(); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if err := l.SaveCache(); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		server.Close()
+
+		l, err = rss.LoadRemoteList(b)
+		if err == nil {
+			t.Fatal("Should report that the server is unreachable")
+		}
+
+		feed = l.FeedIndex["https://example.com/feed.xml"]
+		if feed == nil || len(feed.RssItems) != 2 {
+			t.Fatal("Feeds should be restored from the cache when offline")
+		}
+
+		feed.RssItems[0].ToggleRead()
+		if err := l.PushChanges(); err == nil {
+			t.Error("Push should fail while offline")
+		}
+		if len(feed.Pending()) != 1 {
+			t.Error("Change should stay queued while offline")
+		}
+		l.SaveCache()
+
+		fake, server := newFakeNews(t)
+		b.Url = server.URL
+		l, err = rss.LoadRemoteList(b)
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		feed = l.FeedIndex["https://example.com/feed.xml"]
+		if len(feed.Pending()) != 1 {
+			t.Fatal("Queued change should survive a restart")
+		}
+		if err := l.PushChanges(); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if len(fake.batches["read"]) != 1 {
+			t.Errorf("Queued change not pushed once back online: %v", fake.batches)
+		}
+	})
+}
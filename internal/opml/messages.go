@@ -0,0 +1,5 @@
+package opml
+
+import "errors"
+
+var ErrNotOpml = errors.New("Not an OPML file")
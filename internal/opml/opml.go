@@ -0,0 +1,168 @@
+package opml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/emilosman/rssboat/internal/rss"
+)
+
+// Feeds outside of any outline folder go to this category
+const rootCategory = "Uncategorized"
+
+type document struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    head     `xml:"head"`
+	Body    body     `xml:"body"`
+}
+
+type head struct {
+	Title       string `xml:"title"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type body struct {
+	Outlines []outline `xml:"outline"`
+}
+
+type outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XmlUrl   string    `xml:"xmlUrl,attr,omitempty"`
+	HtmlUrl  string    `xml:"htmlUrl,attr,omitempty"`
+	Outlines []outline `xml:"outline"`
+}
+
+func (o outline) name() string {
+	if o.Title != "" {
+		return o.Title
+	}
+	return o.Text
+}
+
+// Parse reads the feeds of an OPML file. Outline folders become categories,
+// nested folders become nested categories, or with flatten their feeds go to
+// the top folder. Outline titles are kept as feed titles.
+func Parse(r io.Reader, flatten bool) ([]*rss.RssFeed, error) {
+	var doc document
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotOpml, err)
+	}
+	if doc.XMLName.Local != "opml" {
+		return nil, ErrNotOpml
+	}
+
+	var feeds []*rss.RssFeed
+	var walk func(outlines []outline, category string)
+	walk = func(outlines []outline, category string) {
+		for _, o := range outlines {
+			if o.XmlUrl != "" {
+				feed := &rss.RssFeed{Url: o.XmlUrl, Category: category}
+				if category == "" {
+					feed.Category = rootCategory
+				}
+				if name := o.name(); name != o.XmlUrl {
+					feed.Options.Title = name
+				}
+				feeds = append(feeds, feed)
+				continue
+			}
+
+			child := categoryName(o.name())
+			switch {
+			case category == "":
+			case flatten || hasFeeds(outlines):
+				// A folder with its own feeds can't also hold nested
+				// categories in urls.yaml
+				child = category
+			default:
+				child = category + rss.CategorySeparator + child
+			}
+			walk(o.Outlines, child)
+		}
+	}
+	walk(doc.Body.Outlines, "")
+
+	return feeds, nil
+}
+
+func hasFeeds(outlines []outline) bool {
+	for _, o := range outlines {
+		if o.XmlUrl != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// categoryName keeps folder names that contain the separator from nesting
+func categoryName(name string) string {
+	name = strings.TrimSpace(strings.ReplaceAll(name, rss.CategorySeparator, "-"))
+	if name == "" {
+		return rootCategory
+	}
+	return name
+}
+
+// Export writes the list as OPML, nested categories as nested outlines
+func Export(w io.Writer, l *rss.List) error {
+	doc := document{
+		Version: "2.0",
+		Head: head{
+			Title:       "rssboat subscriptions",
+			DateCreated: time.Now().Format(time.RFC1123Z),
+		},
+	}
+
+	folders := make(map[string]*outline)
+	var top []string
+	for _, category := range l.Categories() {
+		folder := &outline{Text: rss.CategoryName(category)}
+		folders[category] = folder
+		if rss.ParentCategory(category) == "" {
+			top = append(top, category)
+		}
+
+		for _, f := range l.CategoryIndex[category] {
+			htmlUrl, _ := f.Link()
+			if htmlUrl == f.Url {
+				htmlUrl = ""
+			}
+			folder.Outlines = append(folder.Outlines, outline{
+				Text:    f.Name(),
+				Title:   f.Name(),
+				Type:    "rss",
+				XmlUrl:  f.Url,
+				HtmlUrl: htmlUrl,
+			})
+		}
+	}
+
+	// Children are attached deepest first, so they're complete when copied
+	categories := l.Categories()
+	for i := len(categories) - 1; i >= 0; i-- {
+		category := categories[i]
+		if parent, ok := folders[rss.ParentCategory(category)]; ok {
+			parent.Outlines = append([]outline{*folders[category]}, parent.Outlines...)
+		}
+	}
+	for _, category := range top {
+		doc.Body.Outlines = append(doc.Body.Outlines, *folders[category])
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
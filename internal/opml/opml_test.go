@@ -0,0 +1,123 @@
+package opml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/emilosman/rssboat/internal/rss"
+)
+
+const subscriptions = `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="1.0">
+  <head><title>Starter pack</title></head>
+  <body>
+    <outline text="Loose" xmlUrl="https://example.com/loose.xml"/>
+    <outline text="Tech">
+      <outline text="Go">
+        <outline text="The Go Blog" title="Go Blog" type="rss" xmlUrl="https://go.dev/blog/feed.atom"/>
+      </outline>
+      <outline text="Rust">
+        <outline text="https://blog.rust-lang.org/feed.xml" xmlUrl="https://blog.rust-lang.org/feed.xml"/>
+      </outline>
+    </outline>
+    <outline text="News">
+      <outline text="World" xmlUrl="https://example.com/world.xml"/>
+      <outline text="Local">
+        <outline text="City" xmlUrl="https://example.com/city.xml"/>
+      </outline>
+    </outline>
+  </body>
+</opml>`
+
+func categories(feeds []*rss.RssFeed) map[string]string {
+	c := make(map[string]string)
+	for _, f := range feeds {
+		c[f.Url] = f.Category
+	}
+	return c
+}
+
+func TestOpml(t *testing.T) {
+	t.Run("Should map outlines to nested categories", func(t *testing.T) {
+		feeds, err := Parse(strings.NewReader(subscriptions), false)
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		got := categories(feeds)
+		want := map[string]string{
+			"https://example.com/loose.xml":       "Uncategorized",
+			"https://go.dev/blog/feed.atom":       "Tech/Go",
+			"https://blog.rust-lang.org/feed.xml": "Tech/Rust",
+			"https://example.com/world.xml":       "News",
+			// News has feeds of its own so Local can't be nested below it
+			"https://example.com/city.xml": "News",
+		}
+		for url, category := range want {
+			if got[url] != category {
+				t.Errorf("%s: got %q, want %q", url, got[url], category)
+			}
+		}
+	})
+
+	t.Run("Should flatten nested outlines", func(t *testing.T) {
+		feeds, _ := Parse(strings.NewReader(subscriptions), true)
+		if got := categories(feeds)["https://go.dev/blog/feed.atom"]; got != "Tech" {
+			t.Errorf("got %q, want Tech", got)
+		}
+	})
+
+	t.Run("Should keep titles that aren't the URL", func(t *testing.T) {
+		feeds, _ := Parse(strings.NewReader(subscriptions), false)
+		for _, f := range feeds {
+			switch f.Url {
+			case "https://go.dev/blog/feed.atom":
+				if f.Options.Title != "Go Blog" {
+					t.Errorf("Wrong title, got %q", f.Options.Title)
+				}
+			case "https://blog.rust-lang.org/feed.xml":
+				if f.Options.Title != "" {
+					t.Errorf("URL should not become a title, got %q", f.Options.Title)
+				}
+			}
+		}
+	})
+
+	t.Run("Should reject other XML", func(t *testing.T) {
+		_, err := Parse(strings.NewReader("<rss></rss>"), false)
+		if err == nil {
+			t.Error("Should raise error for non OPML file")
+		}
+	})
+
+	t.Run("Should export and import the same categories and titles", func(t *testing.T) {
+		l := rss.NewListWithDefaults()
+		l.AddFeeds(
+			&rss.RssFeed{Url: "https://go.dev/blog/feed.atom", Category: "Tech/Go", Options: rss.FeedOptions{Title: "Go Blog"}},
+			&rss.RssFeed{Url: "https://example.com/rust.xml", Category: "Tech/Rust"},
+			&rss.RssFeed{Url: "https://example.com/world.xml", Category: "News"},
+		)
+
+		var buf bytes.Buffer
+		if err := Export(&buf, l); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		feeds, err := Parse(&buf, false)
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if len(feeds) != 3 {
+			t.Fatalf("Wrong number of feeds, got %d", len(feeds))
+		}
+
+		got := categories(feeds)
+		if got["https://go.dev/blog/feed.atom"] != "Tech/Go" || got["https://example.com/world.xml"] != "News" {
+			t.Errorf("Categories not kept: %v", got)
+		}
+		if feeds[0].Options.Title != "Go Blog" {
+			t.Errorf("Title override not kept, got %q", feeds[0].Options.Title)
+		}
+	})
+}
@@ -0,0 +1,246 @@
+package rss
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// Backend is a remote service that owns the subscriptions and item state,
+// such as Miniflux. Feeds loaded from a backend are refreshed through it
+// instead of being fetched directly.
+type Backend interface {
+	// Name is used to keep a separate cache file per backend
+	Name() string
+	// Load adds the remote subscriptions to the list
+	Load(l *List) error
+	// Push sends the feed's pending read and bookmark changes
+	Push(f *RssFeed) error
+	// Sync pushes pending changes, then pulls items and their remote state
+	Sync(f *RssFeed) error
+}
+
+// ItemState is the read and bookmark state last agreed with a backend
+type ItemState struct {
+	Read     bool
+	Bookmark bool
+}
+
+func (i *RssItem) state() ItemState {
+	return ItemState{Read: i.Read, Bookmark: i.Bookmark}
+}
+
+// Pending reports whether the item changed since it was last synced
+func (i *RssItem) Pending() bool {
+	return i.Synced != nil && *i.Synced != i.state()
+}
+
+// MarkSynced records the current state as agreed with the backend
+func (i *RssItem) MarkSynced() {
+	state := i.state()
+	i.Synced = &state
+}
+
+// Pending returns the items with changes not yet pushed to the backend
+func (f *RssFeed) Pending() []*RssItem {
+	var pending []*RssItem
+	for _, item := range f.RssItems {
+		if item.Pending() {
+			pending = append(pending, item)
+		}
+	}
+	return pending
+}
+
+// MergeRemote merges items pulled from a backend. Remote state replaces
+// local state, except for local changes that have not been pushed yet.
+func (f *RssFeed) MergeRemote(items []*RssItem) {
+	f.added = 0
+	existing := make(map[string]*RssItem, len(f.RssItems))
+	for _, item := range f.RssItems {
+		existing[item.Key()] = item
+	}
+	now := time.Now()
+
+	for _, remote := range items {
+		remote.MarkSynced()
+		sanitizeItem(remote.Item)
+
+		item, ok := existing[remote.Key()]
+		if !ok {
+			if remote.FirstSeen == nil {
+				remote.FirstSeen = &now
+			}
+			f.RssItems = append(f.RssItems, remote)
+			existing[remote.Key()] = remote
+			f.added++
+			continue
+		}
+
+		if !item.Pending() {
+			item.SetRead(remote.Read)
+			item.SetBookmark(remote.Bookmark)
+		}
+		item.Synced = remote.Synced
+		item.RemoteId = remote.RemoteId
+		item.Item = remote.Item
+	}
+
+	f.remember(f.RssItems)
+	f.SortByDate()
+}
+
+// pushLocks keeps pushes and syncs of one feed from overlapping, as some
+// backends toggle bookmarks rather than set them
+var pushLocks sync.Map
+
+func pushLock(url string) *sync.Mutex {
+	mu, _ := pushLocks.LoadOrStore(url, &sync.Mutex{})
+	return mu.(*sync.Mutex)
+}
+
+// HasPending reports whether any backend feed has changes not yet pushed
+func (l *List) HasPending() bool {
+	for _, f := range l.Feeds {
+		if f.Backend != nil && len(f.Pending()) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// PushChanges sends pending changes of every backend feed, one push per
+// feed at a time
+func (l *List) PushChanges() error {
+	var firstErr error
+	for _, f := range l.Feeds {
+		if f.Backend == nil {
+			continue
+		}
+		if err := f.push(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (f *RssFeed) push() error {
+	mu := pushLock(f.Url)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(f.Pending()) == 0 {
+		return nil
+	}
+	return f.Backend.Push(f)
+}
+
+// RefreshBookmarks brings the bookmarks feed in line with item state,
+// keeping the order of items that stay bookmarked
+func (l *List) RefreshBookmarks() {
+	bookmarks := l.Bookmarks()
+	if bookmarks == nil {
+		return
+	}
+
+	kept := make(map[*RssItem]struct{}, len(bookmarks.RssItems))
+	var items []*RssItem
+	for _, item := range bookmarks.RssItems {
+		if item.Bookmark {
+			items = append(items, item)
+			kept[item] = struct{}{}
+		}
+	}
+
+	for _, f := range l.Feeds {
+		if f == bookmarks {
+			continue
+		}
+		for _, item := range f.RssItems {
+			if _, ok := kept[item]; item.Bookmark && !ok {
+				items = append(items, item)
+				kept[item] = struct{}{}
+			}
+		}
+	}
+
+	bookmarks.RssItems = items
+	bookmarks.sortByBookmarked()
+}
+
+// LoadRemoteList builds a list from a backend and restores its cache.
+// When the backend can't be reached the list is built from the cache alone,
+// so items can still be read and changes queue up until the next sync.
+func LoadRemoteList(b Backend) (*List, error) {
+	l := NewListWithDefaults()
+	l.Backend = b
+
+	loadErr := b.Load(l)
+	var err error
+	if loadErr != nil {
+		l = NewListWithDefaults()
+		l.Backend = b
+		err = l.restoreCachedFeeds()
+	} else {
+		err = l.restoreCache()
+	}
+
+	for _, f := range l.Feeds {
+		if f != l.Bookmarks() {
+			f.Backend = b
+		}
+	}
+
+	if loadErr != nil {
+		return l, loadErr
+	}
+	return l, err
+}
+
+// LoadCachedRemoteList builds a list of a backend's feeds from the cache
+// alone, without contacting the backend
+func LoadCachedRemoteList(b Backend) (*List, error) {
+	l := NewListWithDefaults()
+	l.Backend = b
+	err := l.restoreCachedFeeds()
+
+	for _, f := range l.Feeds {
+		if f != l.Bookmarks() {
+			f.Backend = b
+		}
+	}
+	return l, err
+}
+
+// restoreCachedFeeds adds every feed found in the cache file to the list
+func (l *List) restoreCachedFeeds() error {
+	cacheFilePath, err := l.CachePath()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(cacheFilePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var decoded List
+	if err := json.NewDecoder(f).Decode(&decoded); err != nil {
+		return err
+	}
+
+	for _, feed := range decoded.Feeds {
+		if feed.Url != "Bookmarks" {
+			l.AddFeeds(feed)
+		}
+	}
+	l.RefreshBookmarks()
+
+	return nil
+}
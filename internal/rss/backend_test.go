@@ -0,0 +1,116 @@
+package rss
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// toggleBackend toggles remote bookmarks on push, like Miniflux does
+type toggleBackend struct {
+	mu      sync.Mutex
+	toggles int
+}
+
+func (b *toggleBackend) Name() string        { return "toggle" }
+func (b *toggleBackend) Load(*List) error    { return nil }
+func (b *toggleBackend) Sync(*RssFeed) error { return nil }
+
+func (b *toggleBackend) Push(f *RssFeed) error {
+	for _, item := range f.Pending() {
+		time.Sleep(10 * time.Millisecond)
+		b.mu.Lock()
+		b.toggles++
+		b.mu.Unlock()
+		item.MarkSynced()
+	}
+	return nil
+}
+
+func TestBackend(t *testing.T) {
+	t.Run("Should keep unpushed local changes when merging remote items", func(t *testing.T) {
+		local := &RssItem{Item: &gofeed.Item{GUID: "a"}}
+		local.MarkSynced()
+		local.ToggleRead()
+
+		f := RssFeed{RssItems: []*RssItem{local}}
+		f.MergeRemote([]*RssItem{
+			{Item: &gofeed.Item{GUID: "a"}, Bookmark: true},
+			{Item: &gofeed.Item{GUID: "b"}, Read: true},
+		})
+
+		if len(f.RssItems) != 2 {
+			t.Fatalf("Wrong number of items, got %d", len(f.RssItems))
+		}
+		if !local.Read {
+			t.Error("Local read change should win over remote state")
+		}
+		if !local.Pending() {
+			t.Error("Local read change should still be pending")
+		}
+		for _, item := range f.RssItems {
+			if item != local && (item.Pending() || !item.Read) {
+				t.Error("New remote item should be synced with remote state")
+			}
+		}
+	})
+
+	t.Run("Should take remote state when nothing is pending", func(t *testing.T) {
+		local := &RssItem{Item: &gofeed.Item{GUID: "a"}}
+		local.MarkSynced()
+
+		f := RssFeed{RssItems: []*RssItem{local}}
+		f.MergeRemote([]*RssItem{{Item: &gofeed.Item{GUID: "a"}, Read: true, Bookmark: true}})
+
+		if !local.Read || !local.Bookmark {
+			t.Error("Remote state should replace local state")
+		}
+	})
+
+	t.Run("Should refresh bookmarks from item state", func(t *testing.T) {
+		l := NewListWithDefaults()
+		kept := &RssItem{Bookmark: true}
+		added := &RssItem{Bookmark: true}
+		removed := &RssItem{}
+		l.Bookmarks().RssItems = []*RssItem{removed, kept}
+		l.Add(&RssFeed{Url: "example.com", RssItems: []*RssItem{added, kept, removed}})
+
+		l.RefreshBookmarks()
+
+		bookmarks := l.Bookmarks().RssItems
+		if len(bookmarks) != 2 || bookmarks[0] != kept || bookmarks[1] != added {
+			t.Errorf("Wrong bookmarks after refresh: %v", bookmarks)
+		}
+	})
+	t.Run("Should push each change once when pushes overlap", func(t *testing.T) {
+		b := &toggleBackend{}
+		item := &RssItem{Item: &gofeed.Item{GUID: "a"}}
+		item.MarkSynced()
+		l := NewListWithDefaults()
+		l.Add(&RssFeed{Url: "example.com/push", Backend: b, RssItems: []*RssItem{item}})
+
+		item.ToggleBookmark()
+		if !l.HasPending() {
+			t.Fatal("Bookmark change should be pending")
+		}
+
+		var wg sync.WaitGroup
+		for range 3 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				l.PushChanges()
+			}()
+		}
+		wg.Wait()
+
+		if b.toggles != 1 {
+			t.Errorf("Bookmark should be pushed once, got %d pushes", b.toggles)
+		}
+		if l.HasPending() {
+			t.Error("Nothing should be pending after pushing")
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package rss
+
+import (
+	"sort"
+	"strings"
+)
+
+// CategorySeparator nests categories, "Tech/Go" is a child of "Tech"
+const CategorySeparator = "/"
+
+func ParentCategory(category string) string {
+	i := strings.LastIndex(category, CategorySeparator)
+	if i == -1 {
+		return ""
+	}
+	return category[:i]
+}
+
+func CategoryDepth(category string) int {
+	return strings.Count(category, CategorySeparator)
+}
+
+// CategoryName is the last part of a nested category
+func CategoryName(category string) string {
+	return category[strings.LastIndex(category, CategorySeparator)+1:]
+}
+
+func isSubcategory(category, parent string) bool {
+	return strings.HasPrefix(category, parent+CategorySeparator)
+}
+
+// Categories returns categories in the order they were added, with parents
+// right before their first child. Categories added without an order, such
+// as those set directly on CategoryIndex, follow alphabetically.
+func (l *List) Categories() []string {
+	var categories []string
+	seen := map[string]bool{}
+	for _, category := range l.leafCategories() {
+		for _, c := range withAncestors(category, "") {
+			if !seen[c] {
+				seen[c] = true
+				categories = append(categories, c)
+			}
+		}
+	}
+
+	return categories
+}
+
+// leafCategories returns the categories holding feeds, in the order of
+// Categories but without the parents that only hold subcategories
+func (l *List) leafCategories() []string {
+	var leaves []string
+	seen := make(map[string]bool, len(l.CategoryIndex))
+	for _, category := range l.categoryOrder {
+		if _, ok := l.CategoryIndex[category]; ok && !seen[category] {
+			seen[category] = true
+			leaves = append(leaves, category)
+		}
+	}
+
+	var rest []string
+	for category := range l.CategoryIndex {
+		if !seen[category] {
+			rest = append(rest, category)
+		}
+	}
+	sort.Strings(rest)
+	return append(leaves, rest...)
+}
+
+// withAncestors returns the category preceded by its parents, outermost
+// first, stopping below top
+func withAncestors(category, top string) []string {
+	chain := []string{category}
+	for parent := ParentCategory(category); parent != "" && parent != top; parent = ParentCategory(parent) {
+		chain = append([]string{parent}, chain...)
+	}
+	return chain
+}
+
+// GetCategory returns the category's feeds followed by the feeds of its
+// subcategories, each feed once and leaving out hidden feeds
+func (l *List) GetCategory(category string) ([]*RssFeed, error) {
+	var feeds []*RssFeed
+
+	if category == "" {
+		return feeds, ErrNoCategoryGiven
+	}
+
+	added := map[*RssFeed]bool{}
+	add := func(c string) {
+		for _, feed := range l.CategoryIndex[c] {
+			if !feed.Options.Hidden && !added[feed] {
+				added[feed] = true
+				feeds = append(feeds, feed)
+			}
+		}
+	}
+
+	add(category)
+	for _, c := range l.leafCategories() {
+		if isSubcategory(c, category) {
+			for _, sub := range withAncestors(c, category) {
+				add(sub)
+			}
+		}
+	}
+
+	return feeds, nil
+}
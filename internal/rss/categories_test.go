@@ -0,0 +1,100 @@
+package rss
+
+import (
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCategories(t *testing.T) {
+	t.Run("Should keep category order from YAML", func(t *testing.T) {
+		l := NewListWithDefaults()
+		fs := fstest.MapFS{
+			"urls.yaml": {Data: []byte("zeta:\n  - https://example.com/z\nalpha:\n  - https://example.com/a\n")},
+		}
+
+		if err := l.CreateFeedsFromYaml(fs, "urls.yaml"); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		want := []string{"zeta", "alpha"}
+		if got := l.Categories(); !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Should nest categories with parents before children", func(t *testing.T) {
+		l := NewListWithDefaults()
+		fs := fstest.MapFS{
+			"urls.yaml": {Data: testData(t, "nested_urls.yaml")},
+		}
+
+		if err := l.CreateFeedsFromYaml(fs, "urls.yaml"); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		want := []string{"news", "tech", "tech/go", "tech/rust", "art", "art/design"}
+		if got := l.Categories(); !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+
+		feeds, _ := l.GetCategory("tech/go")
+		if len(feeds) != 2 || feeds[0].Url != "https://go.dev/blog/feed.atom" {
+			t.Error("Feeds should keep their order from YAML")
+		}
+
+		feeds, _ = l.GetCategory("tech")
+		if len(feeds) != 3 {
+			t.Errorf("Parent category should include feeds of its children, got %d", len(feeds))
+		}
+	})
+
+	t.Run("Should list a parent's feeds before its children's", func(t *testing.T) {
+		l := NewListWithDefaults()
+		l.AddFeeds(
+			&RssFeed{Url: "https://example.com/rust.xml", Category: "tech/rust/async"},
+			&RssFeed{Url: "https://example.com/tech.xml", Category: "tech"},
+			&RssFeed{Url: "https://example.com/lang.xml", Category: "tech/rust"},
+		)
+
+		feeds, _ := l.GetCategory("tech")
+		var got []string
+		for _, f := range feeds {
+			got = append(got, f.Url)
+		}
+		want := []string{"https://example.com/tech.xml", "https://example.com/lang.xml", "https://example.com/rust.xml"}
+		if !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Should reject category with invalid value", func(t *testing.T) {
+		l := NewListWithDefaults()
+		fs := fstest.MapFS{
+			"urls.yaml": {Data: []byte("news: https://example.com/news.rss\n")},
+		}
+
+		err := l.CreateFeedsFromYaml(fs, "urls.yaml")
+		if err == nil {
+			t.Error("Should raise error when category is not a list")
+		}
+	})
+
+	t.Run("Should fall back to alphabetical order for unordered categories", func(t *testing.T) {
+		l := newList()
+
+		want := []string{"Fun", "Serious"}
+		if got := l.Categories(); !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Should name nested categories", func(t *testing.T) {
+		if ParentCategory("tech/go") != "tech" || ParentCategory("tech") != "" {
+			t.Error("Wrong parent category")
+		}
+		if CategoryName("tech/go") != "go" || CategoryDepth("tech/go") != 1 {
+			t.Error("Wrong category name or depth")
+		}
+	})
+}
@@ -0,0 +1,152 @@
+package rss
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// CheckUrls lints urls.yaml. Unlike loading, it reports every problem rather
+// than the first, and also flags invalid URLs, feeds listed twice in a
+// category and tab indentation.
+func CheckUrls(filename string, data []byte) []*ConfigError {
+	problems := tabIndents(filename, data)
+
+	p := &urlsParser{filename: filename, lint: true}
+	if err := p.parse(data); err != nil {
+		if len(problems) > 0 {
+			return problems
+		}
+		if configErr, ok := err.(*ConfigError); ok {
+			return []*ConfigError{configErr}
+		}
+		return []*ConfigError{{File: filename, Err: err}}
+	}
+
+	problems = append(problems, p.problems...)
+	problems = append(problems, p.warnings...)
+	slices.SortStableFunc(problems, func(a, b *ConfigError) int {
+		return a.Line - b.Line
+	})
+	return problems
+}
+
+// CheckUrlsFile lints a urls.yaml file, see CheckUrls, and reports includes
+// that can't be read
+func CheckUrlsFile(filesystem fs.FS, filename string) ([]*ConfigError, error) {
+	data, err := fs.ReadFile(filesystem, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := CheckUrls(filename, data)
+	if len(problems) > 0 {
+		return problems, nil
+	}
+
+	_, err = ReadUrls(filesystem, filename)
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			if configErr, ok := err.(*ConfigError); ok {
+				problems = append(problems, configErr)
+			}
+		}
+	}
+	return problems, nil
+}
+
+// FeedReport is what fetching a feed found out about it
+type FeedReport struct {
+	Feed        *RssFeed
+	Status      string
+	ContentType string
+	Items       int
+	Problems    []error
+}
+
+var probeClient = &http.Client{Timeout: 20 * time.Second}
+
+// ProbeFeed fetches a feed and reports whether it answers, looks like a feed,
+// parses, and has the GUIDs and dates rssboat relies on
+func ProbeFeed(f *RssFeed) FeedReport {
+	r := FeedReport{Feed: f}
+
+	req, err := http.NewRequest(http.MethodGet, f.Url, nil)
+	if err != nil {
+		r.Problems = append(r.Problems, err)
+		return r
+	}
+	userAgent := f.Options.UserAgent
+	if userAgent == "" {
+		userAgent = gofeed.NewParser().UserAgent
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := probeClient.Do(req)
+	if err != nil {
+		r.Problems = append(r.Problems, err)
+		return r
+	}
+	defer resp.Body.Close()
+
+	r.Status = resp.Status
+	r.ContentType = resp.Header.Get("Content-Type")
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		r.Problems = append(r.Problems, fmt.Errorf("%w: %s", ErrFeedStatus, resp.Status))
+		return r
+	}
+	if !feedContentType(r.ContentType) {
+		r.Problems = append(r.Problems, fmt.Errorf("%w: %s", ErrFeedContentType, r.ContentType))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		r.Problems = append(r.Problems, err)
+		return r
+	}
+
+	parsed, err := gofeed.NewParser().ParseString(string(body))
+	if err != nil {
+		r.Problems = append(r.Problems, fmt.Errorf("%w: %w", ErrFeedParse, err))
+		return r
+	}
+
+	r.Items = len(parsed.Items)
+	var noGuid, noDate int
+	for _, item := range parsed.Items {
+		if item.GUID == "" {
+			noGuid++
+		}
+		if item.PublishedParsed == nil && item.UpdatedParsed == nil {
+			noDate++
+		}
+	}
+	if noGuid > 0 {
+		r.Problems = append(r.Problems, fmt.Errorf("%w: %d of %d items", ErrItemsWithoutGuid, noGuid, r.Items))
+	}
+	if noDate > 0 {
+		r.Problems = append(r.Problems, fmt.Errorf("%w: %d of %d items", ErrItemsWithoutDate, noDate, r.Items))
+	}
+
+	return r
+}
+
+// feedContentType accepts the XML and JSON types feeds are served with.
+// Servers often send feeds as text/plain or leave the type out.
+func feedContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "text/plain" || strings.Contains(mediaType, "xml") || strings.Contains(mediaType, "json")
+}
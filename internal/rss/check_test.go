@@ -0,0 +1,118 @@
+package rss
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	t.Run("Should report every problem in line order", func(t *testing.T) {
+		data := []byte(`news:
+  - https://example.com/feed.xml
+  - not a url
+  - url: https://example.com/feed.xml
+    colour: red
+    refresh: soon
+tech:
+  - https://example.com/feed.xml
+`)
+
+		problems := CheckUrls("urls.yaml", data)
+
+		want := []struct {
+			line int
+			err  error
+		}{
+			{3, ErrInvalidUrl},
+			{4, ErrDuplicateFeed},
+			{5, ErrUnknownKey},
+			{6, ErrInvalidDuration},
+		}
+		if len(problems) != len(want) {
+			t.Fatalf("Expected %d problems, got %v", len(want), problems)
+		}
+		for i, w := range want {
+			if problems[i].Line != w.line || !errors.Is(problems[i], w.err) {
+				t.Errorf("Expected %v on line %d, got %v", w.err, w.line, problems[i])
+			}
+		}
+	})
+
+	t.Run("Should report shared feeds hidden in some categories only", func(t *testing.T) {
+		data := []byte(`news:
+  - https://example.com/feed.xml
+tech:
+  - url: https://example.com/feed.xml
+    hidden: true
+`)
+
+		problems := CheckUrls("urls.yaml", data)
+		if len(problems) != 1 || problems[0].Line != 4 || !errors.Is(problems[0], ErrConflictingHidden) {
+			t.Errorf("Expected ErrConflictingHidden on line 4, got %v", problems)
+		}
+	})
+
+	t.Run("Should report feeds outside of categories", func(t *testing.T) {
+		problems := CheckUrls("urls.yaml", []byte("- https://example.com/feed.xml\n"))
+		if len(problems) != 1 || !errors.Is(problems[0], ErrFeedOutsideCategory) {
+			t.Errorf("Expected ErrFeedOutsideCategory, got %v", problems)
+		}
+	})
+
+	t.Run("Should report tab indentation instead of the YAML error", func(t *testing.T) {
+		data := []byte("news:\n\t- https://example.com/feed.xml\n")
+
+		problems := CheckUrls("urls.yaml", data)
+		if len(problems) != 1 || problems[0].Line != 2 || !errors.Is(problems[0], ErrTabIndent) {
+			t.Errorf("Expected ErrTabIndent on line 2, got %v", problems)
+		}
+
+		if _, err := parseUrls("urls.yaml", data); !errors.Is(err, ErrTabIndent) {
+			t.Errorf("Loading should also explain tabs, got %v", err)
+		}
+	})
+
+	t.Run("Should accept a valid file", func(t *testing.T) {
+		problems := CheckUrls("urls.yaml", []byte(ExampleConfigFile+"news:\n  - https://example.com/feed.xml\n"))
+		if len(problems) != 0 {
+			t.Errorf("Expected no problems, got %v", problems)
+		}
+	})
+
+	t.Run("Should probe feeds", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/feed.xml", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/rss+xml")
+			w.Write([]byte(`<rss version="2.0"><channel><title>T</title>
+<item><guid>1</guid><title>A</title><pubDate>Mon, 02 Jan 2006 15:04:05 GMT</pubDate></item>
+<item><title>B</title></item>
+</channel></rss>`))
+		})
+		mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html><body>Not a feed</body></html>"))
+		})
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		r := ProbeFeed(&RssFeed{Url: server.URL + "/feed.xml"})
+		if r.Items != 2 || r.Status != "200 OK" {
+			t.Errorf("Wrong report: %+v", r)
+		}
+		if len(r.Problems) != 2 || !errors.Is(r.Problems[0], ErrItemsWithoutGuid) || !errors.Is(r.Problems[1], ErrItemsWithoutDate) {
+			t.Errorf("Expected missing GUID and date, got %v", r.Problems)
+		}
+
+		r = ProbeFeed(&RssFeed{Url: server.URL + "/page"})
+		if len(r.Problems) != 2 || !errors.Is(r.Problems[0], ErrFeedContentType) || !errors.Is(r.Problems[1], ErrFeedParse) {
+			t.Errorf("Expected content type and parse errors, got %v", r.Problems)
+		}
+
+		r = ProbeFeed(&RssFeed{Url: server.URL + "/missing"})
+		if len(r.Problems) != 1 || !errors.Is(r.Problems[0], ErrFeedStatus) {
+			t.Errorf("Expected ErrFeedStatus, got %v", r.Problems)
+		}
+	})
+}
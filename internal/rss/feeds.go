@@ -3,8 +3,10 @@ package rss
 import (
 	"fmt"
 	"net/url"
+	"slices"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/mmcdole/gofeed"
 )
@@ -12,24 +14,82 @@ import (
 type RssFeed struct {
 	Url      string
 	Category string
-	Error    string
+	// Categories lists every category the feed appears in, starting with Category
+	Categories []string `json:"-"`
+	Error      string
+	RemoteId   int64 `json:",omitempty"`
+	// LastModified is the backend's sync cursor for incremental updates
+	LastModified int64      `json:",omitempty"`
+	FetchedAt    *time.Time `json:",omitempty"`
 
 	Feed     *gofeed.Feed
 	RssItems []*RssItem
+	Backend  Backend     `json:"-"`
+	Options  FeedOptions `json:"-"`
+	// Source is the include the feed comes from, empty for urls.yaml
+	Source string `json:"-"`
+	// Dropped keeps the keys of read items dropped by retention, so fetching
+	// them again doesn't bring them back as unread
+	Dropped []string `json:",omitempty"`
+
+	// Items added by the last fetch
+	added int
+	// Keys of the items the feed has held since it was loaded, so merging
+	// another copy only brings in new items, not ones removed here
+	known map[string]bool
 }
 
 func (f *RssFeed) existingKeys() map[string]struct{} {
 	existing := make(map[string]struct{}, len(f.RssItems))
 	for _, item := range f.RssItems {
-		if item.Item.GUID != "" {
-			existing[item.Item.GUID] = struct{}{}
-		} else if item.Item.Link != "" {
-			existing[item.Item.Link] = struct{}{}
+		if key := item.Key(); key != "" {
+			existing[key] = struct{}{}
 		}
 	}
 	return existing
 }
 
+// remember records the keys of items the feed holds, see known
+func (f *RssFeed) remember(items []*RssItem) {
+	if f.known == nil {
+		f.known = make(map[string]bool, len(items))
+	}
+	for _, item := range items {
+		if key := item.Key(); key != "" {
+			f.known[key] = true
+		}
+	}
+}
+
+func (f *RssFeed) droppedKeys() map[string]bool {
+	dropped := make(map[string]bool, len(f.Dropped))
+	for _, key := range f.Dropped {
+		dropped[key] = true
+	}
+	return dropped
+}
+
+// mergeState copies read and bookmark state from another copy of the feed's items
+func (f *RssFeed) mergeState(items []*RssItem) {
+	byKey := make(map[string]*RssItem, len(f.RssItems))
+	for _, item := range f.RssItems {
+		byKey[item.Key()] = item
+	}
+
+	for _, other := range items {
+		item, ok := byKey[other.Key()]
+		if !ok || other.Key() == "" {
+			continue
+		}
+		if other.Read && !item.Read {
+			item.Read, item.ReadAt = true, other.ReadAt
+		}
+		if other.Bookmark && !item.Bookmark {
+			item.Bookmark, item.BookmarkedAt = true, other.BookmarkedAt
+		}
+	}
+}
+
 func (f *RssFeed) Link() (string, error) {
 	raw := f.Url
 	if f.Feed != nil {
@@ -57,8 +117,8 @@ func (f *RssFeed) SortByDate() {
 			return false
 		}
 
-		ti := f.RssItems[i].Item.PublishedParsed
-		tj := f.RssItems[j].Item.PublishedParsed
+		ti := f.RssItems[i].Date()
+		tj := f.RssItems[j].Date()
 
 		switch {
 		case ti == nil && tj == nil:
@@ -73,6 +133,22 @@ func (f *RssFeed) SortByDate() {
 	})
 }
 
+// sortByBookmarked puts the most recently bookmarked items first
+func (f *RssFeed) sortByBookmarked() {
+	slices.SortStableFunc(f.RssItems, func(a, b *RssItem) int {
+		switch {
+		case a.BookmarkedAt == nil && b.BookmarkedAt == nil:
+			return 0
+		case a.BookmarkedAt == nil:
+			return 1
+		case b.BookmarkedAt == nil:
+			return -1
+		default:
+			return b.BookmarkedAt.Compare(*a.BookmarkedAt)
+		}
+	})
+}
+
 func (f *RssFeed) HasUnread() bool {
 	for i := range f.RssItems {
 		if !f.RssItems[i].Read {
@@ -82,19 +158,24 @@ func (f *RssFeed) HasUnread() bool {
 	return false
 }
 
+func (f *RssFeed) UnreadCount() int {
+	count := 0
+	for _, item := range f.RssItems {
+		if !item.Read {
+			count++
+		}
+	}
+	return count
+}
+
 func (f *RssFeed) MarkAllItemsRead() {
 	for i := range f.RssItems {
-		f.RssItems[i].Read = true
+		f.RssItems[i].SetRead(true)
 	}
 }
 
 func (f *RssFeed) Title() string {
-	var title string
-	if f.Feed == nil || f.Feed.Title == "" {
-		title = f.Url
-	} else {
-		title = f.Feed.Title
-	}
+	title := f.Name()
 
 	if f.HasUnread() {
 		return fmt.Sprintf("+ %s", title)
@@ -103,6 +184,18 @@ func (f *RssFeed) Title() string {
 	return title
 }
 
+// Name is the feed's title from urls.yaml, the feed itself or its URL
+func (f *RssFeed) Name() string {
+	switch {
+	case f.Options.Title != "":
+		return f.Options.Title
+	case f.Feed == nil || f.Feed.Title == "":
+		return f.Url
+	default:
+		return f.Feed.Title
+	}
+}
+
 func (f *RssFeed) Description() string {
 	return f.Feed.Description
 }
@@ -132,7 +225,24 @@ func (f *RssFeed) GetFeed() error {
 		return ErrFeedHasNoUrl
 	}
 
-	parsedFeed, err := gofeed.NewParser().ParseURL(f.Url)
+	if f.Backend != nil {
+		mu := pushLock(f.Url)
+		mu.Lock()
+		defer mu.Unlock()
+		if err := f.Backend.Sync(f); err != nil {
+			f.Error = err.Error()
+			return err
+		}
+		f.fetched()
+		return nil
+	}
+
+	parser := gofeed.NewParser()
+	if f.Options.UserAgent != "" {
+		parser.UserAgent = f.Options.UserAgent
+	}
+
+	parsedFeed, err := parser.ParseURL(f.Url)
 	if err != nil {
 		f.Error = err.Error()
 		return err
@@ -141,12 +251,30 @@ func (f *RssFeed) GetFeed() error {
 	sanitizeFeed(parsedFeed)
 
 	f.Feed = parsedFeed
-	f.mergeItems(parsedFeed.Items)
+	f.added = f.mergeItems(parsedFeed.Items)
 	f.SortByDate()
-	f.Error = ""
+	f.fetched()
 	return nil
 }
 
+func (f *RssFeed) fetched() {
+	now := time.Now()
+	f.FetchedAt = &now
+	f.Error = ""
+	f.applyRetention()
+}
+
+// Due reports whether the feed's refresh interval has passed
+func (f *RssFeed) Due() bool {
+	if f.Options.Hidden {
+		return false
+	}
+	if f.Options.Refresh == 0 || f.FetchedAt == nil {
+		return true
+	}
+	return time.Since(*f.FetchedAt) >= f.Options.Refresh
+}
+
 func sanitizeFeed(f *gofeed.Feed) {
 	f.Title = clean(f.Title)
 	f.Description = clean(f.Description)
@@ -228,8 +356,89 @@ func (f *RssFeed) PrevBefore(current *RssItem) (int, *RssItem) {
 	return -1, nil
 }
 
-func (f *RssFeed) mergeItems(items []*gofeed.Item) {
+// MergeFetched merges another copy of the feed, fetched on its own or saved
+// by another rssboat. Items in both take the read and bookmark state changed
+// last, new items come with theirs, and items removed from this copy since it
+// was loaded stay removed. When the copy was fetched later it also
+// brings the feed details and error, and read items past the feed's
+// retention are dropped. It returns the number of items added.
+func (f *RssFeed) MergeFetched(other *RssFeed) int {
+	later := other.FetchedAt != nil && (f.FetchedAt == nil || other.FetchedAt.After(*f.FetchedAt))
+	if later {
+		f.Feed = other.Feed
+		f.Error = other.Error
+		f.FetchedAt = other.FetchedAt
+	}
+
+	byKey := make(map[string]*RssItem, len(f.RssItems))
+	for _, item := range f.RssItems {
+		byKey[item.Key()] = item
+	}
+	f.remember(f.RssItems)
+	dropped := f.mergeDropped(other, later)
+
+	added := 0
+	for _, item := range other.RssItems {
+		key := item.Key()
+		if key == "" || dropped[key] {
+			continue
+		}
+		if existing, ok := byKey[key]; ok {
+			existing.takeChanges(item)
+			continue
+		}
+		if f.known[key] {
+			// Removed from this copy since it was loaded
+			continue
+		}
+		f.RssItems = append(f.RssItems, item)
+		f.known[key] = true
+		byKey[key] = item
+		added++
+	}
+
+	if added > 0 {
+		f.SortByDate()
+	}
+	if later {
+		f.applyRetention()
+	}
+	f.added = added
+	return added
+}
+
+// mergeDropped takes the keys other dropped by retention and returns every
+// key dropped by either copy. When other was fetched later, keys it neither
+// has nor dropped are no longer served and are forgotten.
+func (f *RssFeed) mergeDropped(other *RssFeed, later bool) map[string]bool {
+	dropped := f.droppedKeys()
+	served := other.existingKeys()
+	for _, key := range other.Dropped {
+		served[key] = struct{}{}
+	}
+
+	var keys []string
+	kept := map[string]bool{}
+	for _, key := range slices.Concat(f.Dropped, other.Dropped) {
+		if _, ok := served[key]; (ok || !later) && !kept[key] {
+			kept[key] = true
+			keys = append(keys, key)
+		}
+		dropped[key] = true
+	}
+	f.Dropped = keys
+	return dropped
+}
+
+// mergeItems adds fetched items the feed doesn't have yet, leaving out
+// those dropped by retention. Dropped keys the feed no longer serves are
+// forgotten, as they can't come back.
+func (f *RssFeed) mergeItems(items []*gofeed.Item) int {
 	existing := f.existingKeys()
+	dropped := f.droppedKeys()
+	f.Dropped = nil
+	now := time.Now()
+	added := 0
 
 	for _, item := range items {
 		key := item.GUID
@@ -237,6 +446,12 @@ func (f *RssFeed) mergeItems(items []*gofeed.Item) {
 			key = item.Link
 		}
 
+		if dropped[key] {
+			delete(dropped, key)
+			existing[key] = struct{}{}
+			f.Dropped = append(f.Dropped, key)
+			continue
+		}
 		if _, ok := existing[key]; ok {
 			continue
 		}
@@ -244,11 +459,16 @@ func (f *RssFeed) mergeItems(items []*gofeed.Item) {
 		sanitizeItem(item)
 
 		f.RssItems = append(f.RssItems, &RssItem{
-			Item: item,
-			Read: false,
+			Item:      item,
+			Read:      false,
+			FirstSeen: &now,
 		})
 		existing[key] = struct{}{}
+		added++
 	}
+
+	f.remember(f.RssItems)
+	return added
 }
 
 func UpdateFeeds(feeds ...*RssFeed) (<-chan FeedResult, error) {
@@ -264,7 +484,7 @@ func UpdateFeeds(feeds ...*RssFeed) (<-chan FeedResult, error) {
 		go func(f *RssFeed) {
 			defer wg.Done()
 			err := f.GetFeed()
-			results <- FeedResult{Feed: f, Err: err}
+			results <- FeedResult{Feed: f, Err: err, New: f.added}
 		}(feed)
 	}
 
@@ -276,6 +496,62 @@ func UpdateFeeds(feeds ...*RssFeed) (<-chan FeedResult, error) {
 	return results, nil
 }
 
+// FetchFeeds fetches copies of the feeds, leaving the feeds alone so nothing
+// else changes them at the same time. The copies are made before it returns,
+// and are merged with RssFeed.MergeCopy where the list is used.
+func FetchFeeds(feeds ...*RssFeed) (<-chan FeedResult, error) {
+	if len(feeds) == 0 {
+		return nil, ErrNoFeedsInList
+	}
+
+	results := make(chan FeedResult, len(feeds))
+	var wg sync.WaitGroup
+	wg.Add(len(feeds))
+
+	for _, feed := range feeds {
+		c := &RssFeed{
+			Url:          feed.Url,
+			RemoteId:     feed.RemoteId,
+			LastModified: feed.LastModified,
+			Backend:      feed.Backend,
+			Options:      feed.Options,
+		}
+		go func(f, c *RssFeed) {
+			defer wg.Done()
+			err := c.GetFeed()
+			results <- FeedResult{Feed: f, Err: err, Fetched: c}
+		}(feed, c)
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results, nil
+}
+
+// MergeCopy merges a copy fetched by FetchFeeds, or keeps the error fetching
+// it, and returns the number of items added
+func (f *RssFeed) MergeCopy(fetched *RssFeed, err error) int {
+	if err != nil {
+		f.Error = err.Error()
+		return 0
+	}
+	if f.Backend == nil {
+		return f.MergeFetched(fetched)
+	}
+
+	// Items come with the backend's state, see MergeRemote
+	if fetched.Feed != nil {
+		f.Feed = fetched.Feed
+	}
+	f.LastModified = max(f.LastModified, fetched.LastModified)
+	f.MergeRemote(fetched.RssItems)
+	f.fetched()
+	return f.added
+}
+
 func MarkFeedsAsRead(feeds ...*RssFeed) {
 	for i := range feeds {
 		feeds[i].MarkAllItemsRead()
@@ -182,7 +182,8 @@ func TestFeeds(t *testing.T) {
 			t.Errorf("Wrong number of feed items, wanted %d, got %d", rawItemCount, len(rssFeed.RssItems))
 		}
 
-		if rssFeed.RssItems[0].Item.Title != "Louisiana Students to Hear from NASA Astronauts Aboard Space Station" {
+		// The undated item was first seen just now, so it sorts first
+		if rssFeed.RssItems[1].Item.Title != "Louisiana Students to Hear from NASA Astronauts Aboard Space Station" {
 			t.Error("Wrong feed item title")
 		}
 
@@ -206,13 +207,46 @@ func TestFeeds(t *testing.T) {
 
 		prevItem := rssFeed.RssItems[0]
 		for i := range rssFeed.RssItems[1:] {
-			if rssFeed.RssItems[i].Item.PublishedParsed.After(*prevItem.Item.PublishedParsed) {
+			if rssFeed.RssItems[i].Date().After(*prevItem.Date()) {
 				t.Error("Wrong order of feed items")
 			}
 			prevItem = rssFeed.RssItems[i]
 		}
 	})
 
+	t.Run("Should sort items without a date by first seen", func(t *testing.T) {
+		older := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		seen := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+		newer := time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)
+
+		undated := &RssItem{Item: &gofeed.Item{}, FirstSeen: &seen}
+		rssFeed := RssFeed{RssItems: []*RssItem{
+			{Item: &gofeed.Item{PublishedParsed: &older}},
+			undated,
+			{Item: &gofeed.Item{PublishedParsed: &newer}},
+		}}
+
+		rssFeed.SortByDate()
+
+		if rssFeed.RssItems[1] != undated {
+			t.Error("Undated item should sort between items by first seen time")
+		}
+	})
+
+	t.Run("Should record first seen time of new items", func(t *testing.T) {
+		server := Server(t, testData(t, "feed.xml"))
+		defer server.Close()
+
+		rssFeed := RssFeed{Url: server.URL}
+		rssFeed.GetFeed()
+
+		for _, item := range rssFeed.RssItems {
+			if item.FirstSeen == nil {
+				t.Fatal("First seen time not recorded")
+			}
+		}
+	})
+
 	t.Run("Should handle sort edge cases", func(t *testing.T) {
 		item1 := &RssItem{}
 		item2 := &RssItem{}
@@ -503,6 +537,41 @@ func TestFeeds(t *testing.T) {
 		}
 	})
 
+	t.Run("Should fetch into copies and leave the feed to merge them", func(t *testing.T) {
+		server := Server(t, testData(t, "feed.xml"))
+		defer server.Close()
+
+		kept := &RssItem{Item: &gofeed.Item{GUID: "kept"}, Read: true}
+		feed := &RssFeed{Url: server.URL, RssItems: []*RssItem{kept}}
+		failing := &RssFeed{Url: ""}
+
+		results, err := FetchFeeds(feed, failing)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for range 2 {
+			res := <-results
+			if len(feed.RssItems) != 1 || feed.FetchedAt != nil {
+				t.Fatal("Feed should not change until the copy is merged")
+			}
+
+			added := res.Feed.MergeCopy(res.Fetched, res.Err)
+			switch res.Feed {
+			case feed:
+				if res.Err != nil || added == 0 || len(feed.RssItems) != added+1 || feed.FetchedAt == nil {
+					t.Errorf("Fetched items should be merged, got %d added of %d", added, len(feed.RssItems))
+				}
+				if !kept.Read {
+					t.Error("Existing items should keep their state")
+				}
+			case failing:
+				if res.Err == nil || failing.Error == "" {
+					t.Error("Failed fetch should be kept as the feed's error")
+				}
+			}
+		}
+	})
+
 	t.Run("Should return the next unread feed correctly", func(t *testing.T) {
 		feed1 := &RssFeed{
 			RssItems: []*RssItem{
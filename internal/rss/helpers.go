@@ -9,7 +9,7 @@ import (
 	"github.com/microcosm-cc/bluemonday"
 )
 
-func CacheFilePath() (string, error) {
+func CacheDirPath() (string, error) {
 	dir, err := os.UserCacheDir()
 	if err != nil {
 		return "", err
@@ -18,6 +18,14 @@ func CacheFilePath() (string, error) {
 	if err := os.MkdirAll(appDir, 0755); err != nil {
 		return "", err
 	}
+	return appDir, nil
+}
+
+func CacheFilePath() (string, error) {
+	appDir, err := CacheDirPath()
+	if err != nil {
+		return "", err
+	}
 	return filepath.Join(appDir, "data.json"), nil
 }
 
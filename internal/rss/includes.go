@@ -0,0 +1,187 @@
+package rss
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// includeKey lists other urls.yaml files at the top of urls.yaml, either paths
+// relative to the config dir or URLs:
+//
+//	include:
+//	  - team.yaml
+//	  - https://example.com/team/urls.yaml
+const includeKey = "include"
+
+type include struct {
+	source string
+	line   int
+}
+
+var includeClient = &http.Client{Timeout: 10 * time.Second}
+
+// ReadUrls reads the feeds of a urls.yaml file followed by the feeds of the
+// files it includes. Feeds listed in both keep the options set locally, so a
+// local entry can hide an included feed or change its title. When an include
+// can't be read the other feeds are still returned, with an error wrapping
+// ErrInclude.
+func ReadUrls(filesystem fs.FS, filename string) ([]*RssFeed, error) {
+	feeds, _, err := ReadUrlsWarnings(filesystem, filename, false)
+	return feeds, err
+}
+
+// ReadCachedUrls is ReadUrls without the network, remote includes are read
+// from their cached copy
+func ReadCachedUrls(filesystem fs.FS, filename string) ([]*RssFeed, error) {
+	feeds, _, err := ReadUrlsWarnings(filesystem, filename, true)
+	return feeds, err
+}
+
+// ReadUrlsWarnings is ReadUrls, or ReadCachedUrls when offline, also
+// returning the problems CheckUrls finds that don't stop the file loading,
+// such as a feed listed twice
+func ReadUrlsWarnings(filesystem fs.FS, filename string, offline bool) ([]*RssFeed, []*ConfigError, error) {
+	data, err := fs.ReadFile(filesystem, filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p := &urlsParser{filename: filename, lint: true}
+	if err := p.parse(data); err != nil {
+		return nil, nil, err
+	}
+	if len(p.problems) > 0 {
+		return nil, nil, p.problems[0]
+	}
+
+	feeds := p.feeds
+	var errs []error
+	for _, inc := range p.includes {
+		included, err := readInclude(filesystem, inc.source, offline)
+		if err != nil {
+			errs = append(errs, &ConfigError{
+				File: filename,
+				Line: inc.line,
+				Err:  fmt.Errorf("%w %s: %w", ErrInclude, inc.source, err),
+			})
+			continue
+		}
+
+		for _, f := range included {
+			f.Source = inc.source
+		}
+		feeds = append(feeds, included...)
+	}
+
+	return feeds, p.warnings, errors.Join(errs...)
+}
+
+func readInclude(filesystem fs.FS, source string, offline bool) ([]*RssFeed, error) {
+	var (
+		data []byte
+		err  error
+	)
+	switch {
+	case strings.HasPrefix(source, "http://"), strings.HasPrefix(source, "https://"):
+		return fetchInclude(source, offline)
+	case strings.HasPrefix(source, "~/"):
+		home, _ := os.UserHomeDir()
+		data, err = os.ReadFile(filepath.Join(home, source[2:]))
+	case filepath.IsAbs(source):
+		data, err = os.ReadFile(source)
+	default:
+		data, err = fs.ReadFile(filesystem, filepath.ToSlash(source))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseInclude(source, data)
+}
+
+func parseInclude(source string, data []byte) ([]*RssFeed, error) {
+	p := &urlsParser{filename: source, included: true}
+	if err := p.parse(data); err != nil {
+		return nil, err
+	}
+	if len(p.problems) > 0 {
+		return nil, p.problems[0]
+	}
+	return p.feeds, nil
+}
+
+// fetchInclude downloads a remote include, keeping a copy in the cache dir to
+// fall back on when offline. Only a download that loads replaces the copy.
+// With offline set only the copy is read.
+func fetchInclude(url string, offline bool) ([]*RssFeed, error) {
+	cached := ""
+	if dir, err := CacheDirPath(); err == nil {
+		sum := sha256.Sum256([]byte(url))
+		cached = filepath.Join(dir, "includes", hex.EncodeToString(sum[:8])+".yaml")
+	}
+
+	if offline {
+		if cached == "" {
+			return nil, ErrIncludeNotCached
+		}
+		data, err := os.ReadFile(cached)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrIncludeNotCached
+		}
+		if err != nil {
+			return nil, err
+		}
+		return parseInclude(url, data)
+	}
+
+	data, fetchErr := download(url)
+	if fetchErr == nil {
+		feeds, err := parseInclude(url, data)
+		if err == nil && cached != "" {
+			cacheInclude(cached, data)
+		}
+		return feeds, err
+	}
+
+	if cached != "" {
+		if data, err := os.ReadFile(cached); err == nil {
+			return parseInclude(url, data)
+		}
+	}
+	return nil, fetchErr
+}
+
+// cacheInclude writes next to the copy and renames, so a failed write keeps
+// the previous copy
+func cacheInclude(path string, data []byte) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		os.Remove(tmp)
+		return
+	}
+	os.Rename(tmp, path)
+}
+
+func download(url string) ([]byte, error) {
+	resp, err := includeClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s", ErrFeedStatus, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
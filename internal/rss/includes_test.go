@@ -0,0 +1,168 @@
+package rss
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestIncludes(t *testing.T) {
+	t.Run("Should merge included feeds with local ones", func(t *testing.T) {
+		filesystem := fstest.MapFS{
+			"urls.yaml": {Data: []byte(`include:
+  - team.yaml
+news:
+  - https://example.com/local.xml
+  - url: https://example.com/hidden.xml
+    hidden: true
+  - url: https://example.com/shared.xml
+    title: Mine
+`)},
+			"team.yaml": {Data: []byte(`news:
+  - https://example.com/shared.xml
+  - https://example.com/hidden.xml
+tech:
+  - https://example.com/team.xml
+`)},
+		}
+
+		l := NewListWithDefaults()
+		if err := l.CreateFeedsFromYaml(filesystem, "urls.yaml"); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		if !slices.Equal(l.Categories(), []string{"news", "tech"}) {
+			t.Errorf("Included categories should merge, got %v", l.Categories())
+		}
+
+		news, _ := l.GetCategory("news")
+		var urls []string
+		for _, f := range news {
+			urls = append(urls, f.Url)
+		}
+		if !slices.Equal(urls, []string{"https://example.com/local.xml", "https://example.com/shared.xml"}) {
+			t.Errorf("Locally hidden feed should not be shown, got %v", urls)
+		}
+
+		if shared := l.FeedIndex["https://example.com/shared.xml"]; shared.Name() != "Mine" || shared.Source != "" {
+			t.Errorf("Local options should override, got %q from %q", shared.Name(), shared.Source)
+		}
+		if team := l.FeedIndex["https://example.com/team.xml"]; team.Source != "team.yaml" {
+			t.Errorf("Included feed should know its source, got %q", team.Source)
+		}
+	})
+
+	t.Run("Should keep local feeds when an include fails", func(t *testing.T) {
+		filesystem := fstest.MapFS{
+			"urls.yaml": {Data: []byte("include: missing.yaml\nnews:\n  - https://example.com/local.xml\n")},
+		}
+
+		feeds, err := ReadUrls(filesystem, "urls.yaml")
+		if !errors.Is(err, ErrInclude) {
+			t.Errorf("Expected ErrInclude, got %v", err)
+		}
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) || configErr.Line != 1 {
+			t.Errorf("Error should point at the include, got %v", err)
+		}
+		if len(feeds) != 1 {
+			t.Errorf("Local feeds should still load, got %d", len(feeds))
+		}
+	})
+
+	t.Run("Should reject nested includes", func(t *testing.T) {
+		filesystem := fstest.MapFS{
+			"urls.yaml": {Data: []byte("include: [team.yaml]\n")},
+			"team.yaml": {Data: []byte("include: [other.yaml]\n")},
+		}
+
+		_, err := ReadUrls(filesystem, "urls.yaml")
+		if !errors.Is(err, ErrNestedInclude) {
+			t.Errorf("Expected ErrNestedInclude, got %v", err)
+		}
+	})
+
+	t.Run("Should use the cached copy of a remote include when offline", func(t *testing.T) {
+		t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+		online := true
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !online {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte("team:\n  - https://example.com/team.xml\n"))
+		}))
+		defer server.Close()
+
+		filesystem := fstest.MapFS{
+			"urls.yaml": {Data: []byte("include:\n  - " + server.URL + "/urls.yaml\n")},
+		}
+
+		for _, online = range []bool{true, false} {
+			feeds, err := ReadUrls(filesystem, "urls.yaml")
+			if err != nil {
+				t.Fatalf("Unexpected error with online %v: %q", online, err)
+			}
+			if len(feeds) != 1 || feeds[0].Source != server.URL+"/urls.yaml" {
+				t.Errorf("Expected the remote feed, got %v", feeds)
+			}
+		}
+	})
+
+	t.Run("Should only cache remote includes that load", func(t *testing.T) {
+		t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+		body := "team:\n  - https://example.com/team.xml\n"
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		}))
+		defer server.Close()
+
+		filesystem := fstest.MapFS{
+			"urls.yaml": {Data: []byte("include:\n  - " + server.URL + "/urls.yaml\n")},
+		}
+
+		ReadUrls(filesystem, "urls.yaml")
+		body = "<html><body>Log in to the Wi-Fi</body></html>"
+		if _, err := ReadUrls(filesystem, "urls.yaml"); !errors.Is(err, ErrInclude) {
+			t.Errorf("Expected ErrInclude for a page that isn't YAML, got %v", err)
+		}
+
+		feeds, err := ReadCachedUrls(filesystem, "urls.yaml")
+		if err != nil || len(feeds) != 1 {
+			t.Errorf("The cached copy should be kept, got %v %v", feeds, err)
+		}
+	})
+
+	t.Run("Should not download remote includes when reading the cache", func(t *testing.T) {
+		t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.Write([]byte("team:\n  - https://example.com/team.xml\n"))
+		}))
+		defer server.Close()
+
+		filesystem := fstest.MapFS{
+			"urls.yaml": {Data: []byte("include:\n  - " + server.URL + "/urls.yaml\n")},
+		}
+
+		if _, err := ReadCachedUrls(filesystem, "urls.yaml"); !errors.Is(err, ErrIncludeNotCached) {
+			t.Errorf("Expected ErrIncludeNotCached, got %v", err)
+		}
+
+		ReadUrls(filesystem, "urls.yaml")
+		feeds, err := ReadCachedUrls(filesystem, "urls.yaml")
+		if err != nil || len(feeds) != 1 {
+			t.Errorf("Expected the cached feed, got %v %v", feeds, err)
+		}
+		if requests != 1 {
+			t.Errorf("Only ReadUrls should download, got %d requests", requests)
+		}
+	})
+}
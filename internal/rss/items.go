@@ -3,6 +3,7 @@ package rss
 import (
 	"fmt"
 	"net/url"
+	"time"
 
 	"github.com/mmcdole/gofeed"
 )
@@ -11,6 +12,16 @@ type RssItem struct {
 	Item     *gofeed.Item
 	Bookmark bool
 	Read     bool
+	RemoteId int64      `json:",omitempty"`
+	Synced   *ItemState `json:",omitempty"`
+
+	FirstSeen    *time.Time `json:",omitempty"`
+	ReadAt       *time.Time `json:",omitempty"`
+	BookmarkedAt *time.Time `json:",omitempty"`
+	// When read and bookmark state last changed, so copies saved by several
+	// rssboats keep the latest change of each
+	ReadChangedAt     *time.Time `json:",omitempty"`
+	BookmarkChangedAt *time.Time `json:",omitempty"`
 }
 
 func (i *RssItem) Link() string {
@@ -33,6 +44,17 @@ func (i *RssItem) Link() string {
 	return url.String()
 }
 
+// Key identifies an item within its feed, the GUID when present or the link otherwise
+func (i *RssItem) Key() string {
+	if i.Item == nil {
+		return ""
+	}
+	if i.Item.GUID != "" {
+		return i.Item.GUID
+	}
+	return i.Item.Link
+}
+
 func (i *RssItem) Title() string {
 	title := i.Item.Title
 	if i.Bookmark {
@@ -48,9 +70,20 @@ func (i *RssItem) FilterContent() string {
 	return fmt.Sprintf("%s %s", i.Title(), i.Description())
 }
 
+// DefaultDateFormat is the layout dates are shown in unless configured otherwise
+const DefaultDateFormat = "2006-01-02 15:04"
+
 func (i *RssItem) Content() string {
+	return i.FormatContent(DefaultDateFormat)
+}
+
+// FormatContent renders the item for reading, with dates in the given layout
+func (i *RssItem) FormatContent(dateFormat string) string {
 	title := i.Title()
-	date := i.Item.PublishedParsed
+	var date string
+	if d := i.Date(); d != nil {
+		date = d.Local().Format(dateFormat)
+	}
 	link := i.Link()
 	desc := i.Description()
 	content := i.Item.Content
@@ -77,16 +110,82 @@ func (i *RssItem) Description() string {
 	return desc
 }
 
+// Date is the published date, or when the item was first seen for items without one
+func (i *RssItem) Date() *time.Time {
+	if i.Item != nil && i.Item.PublishedParsed != nil {
+		return i.Item.PublishedParsed
+	}
+	return i.FirstSeen
+}
+
 func (i *RssItem) ToggleRead() {
-	i.Read = !i.Read
+	if i.Read {
+		i.SetRead(false)
+	} else {
+		i.MarkRead()
+	}
 }
 
 func (i *RssItem) ToggleBookmark() {
-	i.Bookmark = !i.Bookmark
+	i.SetBookmark(!i.Bookmark)
 }
 
+// MarkRead marks an item the user read, recording when for the history
 func (i *RssItem) MarkRead() {
-	i.Read = true
+	if !i.Read {
+		now := time.Now()
+		i.ReadAt = &now
+	}
+	i.SetRead(true)
+}
+
+// SetRead sets the read state, such as from a sync or when marking many
+// items read, without adding the item to the history. Marking it unread
+// forgets when it was read.
+func (i *RssItem) SetRead(read bool) {
+	now := time.Now()
+	switch {
+	case read && !i.Read:
+		i.ReadChangedAt = &now
+	case !read && i.Read:
+		i.ReadAt = nil
+		i.ReadChangedAt = &now
+	}
+	i.Read = read
+}
+
+func (i *RssItem) SetBookmark(bookmark bool) {
+	now := time.Now()
+	switch {
+	case bookmark && !i.Bookmark:
+		i.BookmarkedAt = &now
+		i.BookmarkChangedAt = &now
+	case !bookmark && i.Bookmark:
+		i.BookmarkedAt = nil
+		i.BookmarkChangedAt = &now
+	}
+	i.Bookmark = bookmark
+}
+
+// takeChanges copies read and bookmark state from another copy of the item,
+// each when it changed there last, and reports whether it did
+func (i *RssItem) takeChanges(other *RssItem) bool {
+	changed := false
+	if changedLater(other.ReadChangedAt, i.ReadChangedAt) {
+		i.Read, i.ReadAt = other.Read, other.ReadAt
+		i.ReadChangedAt = other.ReadChangedAt
+		changed = true
+	}
+	if changedLater(other.BookmarkChangedAt, i.BookmarkChangedAt) {
+		i.Bookmark, i.BookmarkedAt = other.Bookmark, other.BookmarkedAt
+		i.BookmarkChangedAt = other.BookmarkChangedAt
+		changed = true
+	}
+	return changed
+}
+
+func changedLater(t, than *time.Time) bool {
+	return t != nil && (than == nil || t.After(*than))
 }
 
 func sanitizeItem(i *gofeed.Item) {
@@ -91,4 +91,51 @@ func TestItems(t *testing.T) {
 			t.Error("Item should be read")
 		}
 	})
+	t.Run("Should record when item was read", func(t *testing.T) {
+		var feedItem RssItem
+
+		feedItem.MarkRead()
+		readAt := feedItem.ReadAt
+		if readAt == nil {
+			t.Fatal("Read time not recorded")
+		}
+
+		feedItem.MarkRead()
+		if feedItem.ReadAt != readAt {
+			t.Error("Read time should not change when already read")
+		}
+
+		feedItem.ToggleRead()
+		if feedItem.ReadAt != nil {
+			t.Error("Read time should be cleared when marked unread")
+		}
+	})
+
+	t.Run("Should not record a read time for items read by a sync or in bulk", func(t *testing.T) {
+		synced := RssItem{Item: &gofeed.Item{GUID: "a"}}
+		synced.SetRead(true)
+		if !synced.Read || synced.ReadAt != nil {
+			t.Error("Item read by a sync should be read without a read time")
+		}
+
+		f := RssFeed{RssItems: []*RssItem{{Item: &gofeed.Item{GUID: "b"}}}}
+		f.MarkAllItemsRead()
+		if f.RssItems[0].ReadAt != nil {
+			t.Error("Items marked read in bulk should stay out of the history")
+		}
+	})
+
+	t.Run("Should record when item was bookmarked", func(t *testing.T) {
+		var feedItem RssItem
+
+		feedItem.ToggleBookmark()
+		if feedItem.BookmarkedAt == nil {
+			t.Fatal("Bookmark time not recorded")
+		}
+
+		feedItem.ToggleBookmark()
+		if feedItem.BookmarkedAt != nil {
+			t.Error("Bookmark time should be cleared when bookmark removed")
+		}
+	})
 }
@@ -2,47 +2,104 @@ package rss
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"io/fs"
 	"os"
+	"path/filepath"
 	"slices"
-	"sort"
-
-	yaml "github.com/goccy/go-yaml"
 )
 
 type List struct {
 	Feeds         []*RssFeed
 	FeedIndex     map[string]*RssFeed   `json:"-"`
 	CategoryIndex map[string][]*RssFeed `json:"-"`
+	Backend       Backend               `json:"-"`
+	// Warnings are the problems of urls.yaml that didn't stop it loading
+	Warnings []*ConfigError `json:"-"`
+
+	categoryOrder []string
 }
 
 type FeedResult struct {
 	Feed *RssFeed
 	Err  error
+	// Number of new items
+	New int
+	// Fetched is the copy FetchFeeds fetched into, see RssFeed.MergeCopy
+	Fetched *RssFeed
+}
+
+func (l *List) Add(feeds ...*RssFeed) {
+	l.Feeds = append(l.Feeds, feeds...)
 }
 
-func (l *List) Categories() []string {
-	var categories []string
-	for category := range l.CategoryIndex {
-		categories = append(categories, category)
+// AddFeeds adds feeds to the list and indexes them by URL and category.
+// A URL that is already in the list is added to the new category, sharing
+// the existing feed and its state.
+func (l *List) AddFeeds(feeds ...*RssFeed) {
+	for _, feed := range feeds {
+		category := feed.Category
+		if _, ok := l.CategoryIndex[category]; !ok {
+			l.categoryOrder = append(l.categoryOrder, category)
+		}
+
+		existing := l.FeedIndex[feed.Url]
+		if existing == nil {
+			feed.Categories = []string{feed.Category}
+			l.FeedIndex[feed.Url] = feed
+			l.Feeds = append(l.Feeds, feed)
+		} else {
+			existing.Options.merge(feed.Options)
+			if slices.Contains(existing.Categories, category) {
+				continue
+			}
+			existing.Categories = append(existing.Categories, category)
+			feed = existing
+		}
+
+		l.CategoryIndex[category] = append(l.CategoryIndex[category], feed)
 	}
-	sort.Strings(categories)
-	return categories
 }
 
-func (l *List) GetCategory(category string) ([]*RssFeed, error) {
-	var feeds []*RssFeed
+// Reconcile replaces the list's feeds with feeds read again from urls.yaml.
+// Feeds that are still listed carry on as the same *RssFeed with their items
+// and state, taking the new categories and options.
+func (l *List) Reconcile(feeds []*RssFeed) (added, removed []*RssFeed) {
+	old, oldFeeds := l.FeedIndex, l.Feeds
+	bookmarks := l.Bookmarks()
 
-	if category == "" {
-		return feeds, ErrNoCategoryGiven
+	l.Feeds = []*RssFeed{bookmarks}
+	l.FeedIndex = map[string]*RssFeed{"Bookmarks": bookmarks}
+	l.CategoryIndex = map[string][]*RssFeed{}
+	l.categoryOrder = nil
+
+	for _, feed := range feeds {
+		existing, ok := old[feed.Url]
+		switch {
+		case l.FeedIndex[feed.Url] != nil:
+			// Another category listing the same feed
+		case ok && existing != bookmarks:
+			existing.Category = feed.Category
+			existing.Options = feed.Options
+			existing.Source = feed.Source
+			feed = existing
+		default:
+			feed.Backend = l.Backend
+			added = append(added, feed)
+		}
+		l.AddFeeds(feed)
 	}
 
-	return l.CategoryIndex[category], nil
-}
+	for _, feed := range oldFeeds {
+		if _, ok := l.FeedIndex[feed.Url]; !ok {
+			removed = append(removed, feed)
+		}
+	}
 
-func (l *List) Add(feeds ...*RssFeed) {
-	l.Feeds = append(l.Feeds, feeds...)
+	l.RefreshBookmarks()
+	return added, removed
 }
 
 func (l *List) Bookmarks() *RssFeed {
@@ -58,7 +115,7 @@ func (l *List) ToggleBookmark(item *RssItem) (added bool, err error) {
 	item.ToggleBookmark()
 
 	if item.Bookmark {
-		bookmarks.RssItems = append(bookmarks.RssItems, item)
+		bookmarks.RssItems = append([]*RssItem{item}, bookmarks.RssItems...)
 		return true, nil
 	}
 
@@ -70,40 +127,57 @@ func (l *List) ToggleBookmark(item *RssItem) (added bool, err error) {
 	return false, nil
 }
 
+// UpdateAllFeeds refreshes every feed that is due, see RssFeed.Due
 func (l *List) UpdateAllFeeds() (<-chan FeedResult, error) {
-	return UpdateFeeds(l.Feeds...)
-}
-
-func (l *List) CreateFeedsFromYaml(filesystem fs.FS, filename string) error {
-	file, err := filesystem.Open(filename)
-	if err != nil {
-		return err
+	feeds, ok := l.dueFeeds()
+	if !ok {
+		return noResults(), nil
 	}
-	defer file.Close()
-
-	data, _ := io.ReadAll(file)
+	return UpdateFeeds(feeds...)
+}
 
-	var raw map[string][]string
-	if err := yaml.Unmarshal(data, &raw); err != nil {
-		return err
+// FetchAllFeeds is UpdateAllFeeds fetching into copies, see FetchFeeds
+func (l *List) FetchAllFeeds() (<-chan FeedResult, error) {
+	feeds, ok := l.dueFeeds()
+	if !ok {
+		return noResults(), nil
 	}
+	return FetchFeeds(feeds...)
+}
 
-	var feeds []*RssFeed
-	for category, urls := range raw {
-		for _, u := range urls {
-			feed := &RssFeed{
-				Url:      u,
-				Category: category,
-			}
-			l.FeedIndex[u] = feed
-			l.CategoryIndex[category] = append(l.CategoryIndex[category], feed)
+// dueFeeds returns the feeds that are due, ok is false when the list has
+// feeds but none are due
+func (l *List) dueFeeds() (feeds []*RssFeed, ok bool) {
+	subscribed := 0
+	for _, feed := range l.Feeds {
+		if feed == l.Bookmarks() {
+			continue
+		}
+		subscribed++
+		if feed.Due() {
 			feeds = append(feeds, feed)
 		}
 	}
+	return feeds, subscribed == 0 || len(feeds) > 0
+}
 
-	l.Feeds = append(l.Feeds, feeds...)
+func noResults() <-chan FeedResult {
+	results := make(chan FeedResult)
+	close(results)
+	return results
+}
 
-	return nil
+// CreateFeedsFromYaml adds the feeds of a urls.yaml file and its includes.
+// Feeds are added even when an include fails, see ReadUrls.
+func (l *List) CreateFeedsFromYaml(filesystem fs.FS, filename string) error {
+	return l.readUrls(filesystem, filename, false)
+}
+
+func (l *List) readUrls(filesystem fs.FS, filename string, offline bool) error {
+	feeds, warnings, err := ReadUrlsWarnings(filesystem, filename, offline)
+	l.AddFeeds(feeds...)
+	l.Warnings = warnings
+	return err
 }
 
 func (l *List) MarkAllFeedsRead() {
@@ -129,6 +203,56 @@ func (l *List) Save(w io.Writer) error {
 	return err
 }
 
+// CachePath is the list's cache file, data.json for urls.yaml feeds
+func (l *List) CachePath() (string, error) {
+	if l.Backend == nil {
+		return CacheFilePath()
+	}
+
+	appDir, err := CacheDirPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(appDir, fmt.Sprintf("data.%s.json", l.Backend.Name())), nil
+}
+
+// SaveCache writes the list to its cache file. Changes another rssboat saved
+// in the meantime are merged first, see Merge, while holding a lock so saves
+// don't overlap. The file is replaced in one step, so another rssboat
+// reading it never sees half of it.
+func (l *List) SaveCache() error {
+	cacheFilePath, err := l.CachePath()
+	if err != nil {
+		return err
+	}
+
+	lock, err := LockFile(cacheFilePath+".lock", true)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	// A cache that doesn't decode is replaced
+	l.MergeCache()
+
+	tmp := cacheFilePath + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	if err := l.Save(f); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, cacheFilePath)
+}
+
 /*
 Restore from file
 
@@ -149,26 +273,97 @@ func (l *List) Restore(r io.Reader) error {
 		return err
 	}
 
+	restored := make(map[string]bool)
 	for _, decodedFeed := range decoded.Feeds {
 		if decodedFeed.Url == "Bookmarks" {
 			continue
 		}
 
 		feed := l.FeedIndex[decodedFeed.Url]
+		if feed != nil && restored[feed.Url] {
+			// Older caches hold a copy of the feed per category
+			feed.mergeState(decodedFeed.RssItems)
+			continue
+		}
 		if feed != nil {
+			restored[feed.Url] = true
 			feed.Error = decodedFeed.Error
 			feed.Feed = decodedFeed.Feed
 			feed.RssItems = decodedFeed.RssItems
+			feed.remember(feed.RssItems)
+			feed.LastModified = decodedFeed.LastModified
+			feed.FetchedAt = decodedFeed.FetchedAt
+		}
+	}
+
+	l.RefreshBookmarks()
+
+	return nil
+}
+
+// Merge brings in items and read and bookmark changes from another copy of
+// the list, such as the cache saved by rssboat refresh or another window,
+// see RssFeed.MergeFetched. It returns the number of items added.
+func (l *List) Merge(r io.Reader) (int, error) {
+	var decoded List
+	if err := json.NewDecoder(r).Decode(&decoded); err != nil {
+		return 0, err
+	}
+
+	added := 0
+	for _, other := range decoded.Feeds {
+		feed := l.FeedIndex[other.Url]
+		if feed != nil && feed != l.Bookmarks() {
+			added += feed.MergeFetched(other)
+		}
+	}
+
+	l.RefreshBookmarks()
+	return added, nil
+}
+
+// MergeCache merges the list's cache file, see Merge
+func (l *List) MergeCache() (int, error) {
+	cacheFilePath, err := l.CachePath()
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := os.Open(cacheFilePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	return l.Merge(f)
+}
 
-			for _, item := range feed.RssItems {
-				if item.Bookmark {
-					l.Bookmarks().RssItems = append(l.Bookmarks().RssItems, item)
-				}
+// History returns a feed of read items, most recently read first
+func (l *List) History(limit int) *RssFeed {
+	history := &RssFeed{Url: "History"}
+	for _, feed := range l.Feeds {
+		if feed == l.Bookmarks() {
+			continue
+		}
+		for _, item := range feed.RssItems {
+			if item.ReadAt != nil {
+				history.RssItems = append(history.RssItems, item)
 			}
 		}
 	}
 
-	return nil
+	slices.SortStableFunc(history.RssItems, func(a, b *RssItem) int {
+		return b.ReadAt.Compare(*a.ReadAt)
+	})
+
+	if len(history.RssItems) > limit {
+		history.RssItems = history.RssItems[:limit]
+	}
+
+	return history
 }
 
 func NewListWithDefaults() *List {
@@ -186,25 +381,46 @@ func LoadList(filesystem fs.FS) (*List, error) {
 	l := NewListWithDefaults()
 
 	err := l.CreateFeedsFromYaml(filesystem, "urls.yaml")
-	if err != nil {
+	if err != nil && !errors.Is(err, ErrInclude) {
 		return l, err
 	}
 
-	cacheFilePath, err := CacheFilePath()
-	if err != nil {
+	if cacheErr := l.restoreCache(); cacheErr != nil {
+		return l, cacheErr
+	}
+	return l, err
+}
+
+// LoadCachedList loads the list like LoadList without using the network,
+// for a quick look at the cache
+func LoadCachedList(filesystem fs.FS) (*List, error) {
+	l := NewListWithDefaults()
+
+	err := l.readUrls(filesystem, "urls.yaml", true)
+	if err != nil && !errors.Is(err, ErrInclude) {
 		return l, err
 	}
 
-	f, err := os.Open(cacheFilePath)
+	if cacheErr := l.restoreCache(); cacheErr != nil {
+		return l, cacheErr
+	}
+	return l, err
+}
+
+func (l *List) restoreCache() error {
+	cacheFilePath, err := l.CachePath()
 	if err != nil {
-		return l, err
+		return err
 	}
-	defer f.Close()
 
-	err = l.Restore(f)
+	f, err := os.Open(cacheFilePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
 	if err != nil {
-		return l, err
+		return err
 	}
+	defer f.Close()
 
-	return l, nil
+	return l.Restore(f)
 }
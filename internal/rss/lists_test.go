@@ -2,6 +2,8 @@ package rss
 
 import (
 	"bytes"
+	"errors"
+	"os"
 	"slices"
 	"testing"
 	"testing/fstest"
@@ -379,4 +381,344 @@ func TestLists(t *testing.T) {
 			t.Error("Item should not be in bookmarks")
 		}
 	})
+	t.Run("Should list most recently read items in history", func(t *testing.T) {
+		first := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		second := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+		older := &RssItem{Read: true, ReadAt: &first}
+		newer := &RssItem{Read: true, ReadAt: &second}
+
+		l := NewListWithDefaults()
+		l.Add(&RssFeed{RssItems: []*RssItem{older, {}, newer}})
+
+		history := l.History(10).RssItems
+		if len(history) != 2 || history[0] != newer || history[1] != older {
+			t.Errorf("Wrong history returned: %v", history)
+		}
+
+		if len(l.History(1).RssItems) != 1 {
+			t.Error("History should be limited")
+		}
+	})
+
+	t.Run("Should restore bookmarks most recently bookmarked first", func(t *testing.T) {
+		first := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		second := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+
+		saved := NewListWithDefaults()
+		saved.AddFeeds(&RssFeed{Url: "example.com", RssItems: []*RssItem{
+			{Item: &gofeed.Item{Title: "older"}, Bookmark: true, BookmarkedAt: &first},
+			{Item: &gofeed.Item{Title: "newer"}, Bookmark: true, BookmarkedAt: &second},
+		}})
+
+		var buf bytes.Buffer
+		saved.Save(&buf)
+
+		l := NewListWithDefaults()
+		l.AddFeeds(&RssFeed{Url: "example.com"})
+		if err := l.Restore(&buf); err != nil {
+			t.Fatalf("Unexpected error restoring: %q", err)
+		}
+
+		bookmarks := l.Bookmarks().RssItems
+		if len(bookmarks) != 2 || bookmarks[0].Item.Title != "newer" {
+			t.Error("Bookmarks should be sorted by bookmark time")
+		}
+	})
+
+	t.Run("Should share one feed between categories", func(t *testing.T) {
+		l := NewListWithDefaults()
+		fs := fstest.MapFS{
+			"urls.yaml": {Data: []byte(`news:
+  - url: https://example.com/feed.xml
+    tags: [daily]
+tech:
+  - url: https://example.com/feed.xml
+    tags: [go]
+`)},
+		}
+
+		if err := l.CreateFeedsFromYaml(fs, "urls.yaml"); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		news, _ := l.GetCategory("news")
+		tech, _ := l.GetCategory("tech")
+		if len(news) != 1 || len(tech) != 1 || news[0] != tech[0] {
+			t.Fatal("Both categories should hold the same feed")
+		}
+		if len(l.Feeds) != 2 {
+			t.Errorf("Feed should be listed once, got %d feeds", len(l.Feeds))
+		}
+
+		feed := news[0]
+		if !slices.Equal(feed.Categories, []string{"news", "tech"}) {
+			t.Errorf("Wrong categories, got %v", feed.Categories)
+		}
+		if !slices.Equal(feed.Options.Tags, []string{"daily", "go"}) {
+			t.Errorf("Tags should be combined, got %v", feed.Options.Tags)
+		}
+	})
+
+	t.Run("Should merge state from older caches with duplicated feeds", func(t *testing.T) {
+		var buf bytes.Buffer
+		saved := List{Feeds: []*RssFeed{
+			{Url: "example.com", Category: "news", RssItems: []*RssItem{{Item: &gofeed.Item{GUID: "a"}, Read: true}}},
+			{Url: "example.com", Category: "tech", RssItems: []*RssItem{{Item: &gofeed.Item{GUID: "a"}, Bookmark: true}}},
+		}}
+		saved.Save(&buf)
+
+		l := NewListWithDefaults()
+		l.AddFeeds(&RssFeed{Url: "example.com", Category: "news"}, &RssFeed{Url: "example.com", Category: "tech"})
+		if err := l.Restore(&buf); err != nil {
+			t.Fatalf("Unexpected error restoring: %q", err)
+		}
+
+		item := l.FeedIndex["example.com"].RssItems[0]
+		if !item.Read || !item.Bookmark {
+			t.Error("Read and bookmark state of both copies should be kept")
+		}
+		if len(l.Bookmarks().RssItems) != 1 {
+			t.Error("Merged bookmark should be in bookmarks")
+		}
+	})
+
+	t.Run("Should reconcile feeds keeping state of unchanged ones", func(t *testing.T) {
+		l := NewListWithDefaults()
+		kept := &RssFeed{Url: "https://example.com/kept.xml", Category: "news"}
+		gone := &RssFeed{Url: "https://example.com/gone.xml", Category: "news"}
+		l.AddFeeds(kept, gone)
+		item := &RssItem{Item: &gofeed.Item{GUID: "a"}, Bookmark: true}
+		kept.RssItems = []*RssItem{item}
+		gone.RssItems = []*RssItem{{Item: &gofeed.Item{GUID: "b"}, Bookmark: true}}
+		l.RefreshBookmarks()
+
+		added, removed := l.Reconcile([]*RssFeed{
+			{Url: "https://example.com/new.xml", Category: "tech"},
+			{Url: "https://example.com/kept.xml", Category: "tech", Options: FeedOptions{Title: "Kept"}},
+			{Url: "https://example.com/kept.xml", Category: "news"},
+		})
+
+		if len(added) != 1 || added[0].Url != "https://example.com/new.xml" {
+			t.Errorf("Wrong added feeds: %v", added)
+		}
+		if len(removed) != 1 || removed[0] != gone {
+			t.Errorf("Wrong removed feeds: %v", removed)
+		}
+
+		if l.FeedIndex[kept.Url] != kept || kept.RssItems[0] != item {
+			t.Error("Unchanged feed should keep its items")
+		}
+		if kept.Name() != "Kept" || !slices.Equal(kept.Categories, []string{"tech", "news"}) {
+			t.Errorf("Options and categories should be updated, got %q %v", kept.Name(), kept.Categories)
+		}
+		if !slices.Equal(l.Categories(), []string{"tech", "news"}) {
+			t.Errorf("Categories should follow the new order, got %v", l.Categories())
+		}
+		if len(l.Feeds) != 3 || l.Feeds[0] != l.Bookmarks() {
+			t.Errorf("Expected bookmarks and two feeds, got %d", len(l.Feeds))
+		}
+		if len(l.Bookmarks().RssItems) != 2 {
+			t.Error("Bookmarks of removed feeds should be kept")
+		}
+	})
+
+	t.Run("Should merge items fetched by another rssboat", func(t *testing.T) {
+		var buf bytes.Buffer
+		later := time.Now()
+		saved := NewListWithDefaults()
+		saved.AddFeeds(&RssFeed{
+			Url:       "example.com",
+			Category:  "news",
+			FetchedAt: &later,
+			Feed:      &gofeed.Feed{Title: "Fetched"},
+			RssItems: []*RssItem{
+				{Item: &gofeed.Item{GUID: "a"}, Read: true},
+				{Item: &gofeed.Item{GUID: "b"}, Bookmark: true},
+			},
+		})
+		saved.Save(&buf)
+
+		l := NewListWithDefaults()
+		earlier := later.Add(-time.Hour)
+		kept := &RssItem{Item: &gofeed.Item{GUID: "a"}}
+		l.AddFeeds(&RssFeed{Url: "example.com", Category: "news", FetchedAt: &earlier, RssItems: []*RssItem{kept}})
+
+		added, err := l.Merge(&buf)
+		if err != nil {
+			t.Fatalf("Unexpected error merging: %q", err)
+		}
+
+		feed := l.FeedIndex["example.com"]
+		if added != 1 || len(feed.RssItems) != 2 {
+			t.Fatalf("Expected one new item, got %d", added)
+		}
+		if kept.Read || feed.RssItems[0] != kept && feed.RssItems[1] != kept {
+			t.Error("Existing item should keep its state")
+		}
+		if feed.Name() != "Fetched" || !feed.FetchedAt.Equal(later) {
+			t.Error("Feed fetched later should take the saved details")
+		}
+		if len(l.Bookmarks().RssItems) != 1 {
+			t.Error("New bookmarked item should be in bookmarks")
+		}
+	})
+
+	t.Run("Should save the cache without leaving a temporary file", func(t *testing.T) {
+		dir := t.TempDir()
+		t.Setenv("XDG_CACHE_HOME", dir)
+
+		l := NewListWithDefaults()
+		l.AddFeeds(&RssFeed{Url: "example.com", Category: "news", RssItems: []*RssItem{{Item: &gofeed.Item{GUID: "a"}}}})
+		if err := l.SaveCache(); err != nil {
+			t.Fatalf("Unexpected error saving: %q", err)
+		}
+
+		path, _ := l.CachePath()
+		if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+			t.Error("Temporary file should be renamed")
+		}
+
+		restored := NewListWithDefaults()
+		restored.AddFeeds(&RssFeed{Url: "example.com", Category: "news"})
+		if err := restored.restoreCache(); err != nil || len(restored.FeedIndex["example.com"].RssItems) != 1 {
+			t.Errorf("Saved cache should restore, got %v", err)
+		}
+	})
+
+	t.Run("Should keep item changes saved by another rssboat", func(t *testing.T) {
+		t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+		openList := func() *List {
+			l := NewListWithDefaults()
+			l.AddFeeds(&RssFeed{Url: "example.com", Category: "news", RssItems: []*RssItem{
+				{Item: &gofeed.Item{GUID: "a"}},
+				{Item: &gofeed.Item{GUID: "b"}},
+			}})
+			return l
+		}
+		first, second := openList(), openList()
+
+		first.FeedIndex["example.com"].RssItems[0].MarkRead()
+		if err := first.SaveCache(); err != nil {
+			t.Fatalf("Unexpected error saving: %q", err)
+		}
+		second.ToggleBookmark(second.FeedIndex["example.com"].RssItems[1])
+		if err := second.SaveCache(); err != nil {
+			t.Fatalf("Unexpected error saving: %q", err)
+		}
+
+		restored := openList()
+		if err := restored.restoreCache(); err != nil {
+			t.Fatalf("Unexpected error restoring: %q", err)
+		}
+		items := restored.FeedIndex["example.com"].RssItems
+		if !items[0].Read {
+			t.Error("Item read in the first list should stay read")
+		}
+		if !items[1].Bookmark || len(restored.Bookmarks().RssItems) != 1 {
+			t.Error("Item bookmarked in the second list should stay bookmarked")
+		}
+
+		first.FeedIndex["example.com"].RssItems[0].SetRead(false)
+		first.SaveCache()
+		second.MergeCache()
+		if second.FeedIndex["example.com"].RssItems[0].Read {
+			t.Error("Item marked unread later should be unread")
+		}
+	})
+
+	t.Run("Should keep read and bookmark changes of the same item from each rssboat", func(t *testing.T) {
+		t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+		openList := func() *List {
+			l := NewListWithDefaults()
+			l.AddFeeds(&RssFeed{Url: "example.com", Category: "news", RssItems: []*RssItem{
+				{Item: &gofeed.Item{GUID: "a"}},
+			}})
+			return l
+		}
+		first, second := openList(), openList()
+
+		first.FeedIndex["example.com"].RssItems[0].MarkRead()
+		if err := first.SaveCache(); err != nil {
+			t.Fatalf("Unexpected error saving: %q", err)
+		}
+		second.ToggleBookmark(second.FeedIndex["example.com"].RssItems[0])
+		if err := second.SaveCache(); err != nil {
+			t.Fatalf("Unexpected error saving: %q", err)
+		}
+
+		restored := openList()
+		if err := restored.restoreCache(); err != nil {
+			t.Fatalf("Unexpected error restoring: %q", err)
+		}
+		item := restored.FeedIndex["example.com"].RssItems[0]
+		if !item.Read {
+			t.Error("Item read in the first list should stay read")
+		}
+		if !item.Bookmark {
+			t.Error("Item bookmarked in the second list should be bookmarked")
+		}
+	})
+
+	t.Run("Should not bring back items removed since the cache was loaded", func(t *testing.T) {
+		t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+		openList := func() *List {
+			l := NewListWithDefaults()
+			l.AddFeeds(&RssFeed{Url: "example.com", Category: "news", Options: FeedOptions{Retention: Retention{Count: 1}}})
+			return l
+		}
+		saved := openList()
+		saved.FeedIndex["example.com"].RssItems = []*RssItem{
+			{Item: &gofeed.Item{GUID: "a"}, Read: true},
+			{Item: &gofeed.Item{GUID: "b"}, Read: true},
+			{Item: &gofeed.Item{GUID: "c"}, Read: true},
+		}
+		if err := saved.SaveCache(); err != nil {
+			t.Fatalf("Unexpected error saving: %q", err)
+		}
+
+		l := openList()
+		l.restoreCache()
+		feed := l.FeedIndex["example.com"]
+		feed.applyRetention()
+		if err := l.SaveCache(); err != nil {
+			t.Fatalf("Unexpected error saving: %q", err)
+		}
+		if len(feed.RssItems) != 1 {
+			t.Errorf("Items dropped by retention should stay dropped, got %d", len(feed.RssItems))
+		}
+
+		feed.Dropped = nil
+		feed.RssItems = nil
+		if err := l.SaveCache(); err != nil {
+			t.Fatalf("Unexpected error saving: %q", err)
+		}
+		restored := openList()
+		restored.restoreCache()
+		if len(feed.RssItems) != 0 || len(restored.FeedIndex["example.com"].RssItems) != 0 {
+			t.Errorf("Removed items should stay removed, got %d", len(feed.RssItems))
+		}
+	})
+
+	t.Run("Should lock the list for one instance", func(t *testing.T) {
+		t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+		l := NewListWithDefaults()
+		lock, err := l.LockInstance()
+		if err != nil {
+			t.Fatalf("Unexpected error locking: %q", err)
+		}
+		if _, err := l.LockInstance(); !errors.Is(err, ErrLocked) {
+			t.Errorf("Expected ErrLocked, got %v", err)
+		}
+
+		lock.Unlock()
+		lock, err = l.LockInstance()
+		if err != nil {
+			t.Fatalf("Expected the lock after unlocking, got %q", err)
+		}
+		lock.Unlock()
+	})
 }
@@ -0,0 +1,39 @@
+package rss
+
+import (
+	"os"
+)
+
+// FileLock is an advisory lock on a file, held by one rssboat at a time
+type FileLock struct {
+	f *os.File
+}
+
+// LockFile takes the lock on path, creating the file if needed. With wait
+// unset it returns ErrLocked instead of waiting for another rssboat.
+func LockFile(path string, wait bool) (*FileLock, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f, wait); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &FileLock{f: f}, nil
+}
+
+func (l *FileLock) Unlock() error {
+	unlockFile(l.f)
+	return l.f.Close()
+}
+
+// LockInstance marks the list's cache as open for as long as the lock is
+// held. It returns ErrLocked when another rssboat has it open.
+func (l *List) LockInstance() (*FileLock, error) {
+	cacheFilePath, err := l.CachePath()
+	if err != nil {
+		return nil, err
+	}
+	return LockFile(cacheFilePath+".open", false)
+}
@@ -3,13 +3,42 @@ package rss
 import "errors"
 
 var (
-	ErrFeedHasNoUrl       = errors.New("Feed has no URL")
-	ErrNoFeedsInList      = errors.New("No feeds in list")
-	ErrNoCategoryGiven    = errors.New("No category given")
-	ErrNoBookmarkFeed     = errors.New("No bookmark feed found")
-	ErrConfigDoesNotExist = "open urls.yaml: file does not exist"
-	MsgFeedNotLoaded      = "Feed not loaded yet. Press shift+r"
-	ExampleConfigFile     = `# This file is written in YAML format.
+	ErrFeedHasNoUrl        = errors.New("Feed has no URL")
+	ErrNoFeedsInList       = errors.New("No feeds in list")
+	ErrNoCategoryGiven     = errors.New("No category given")
+	ErrNoBookmarkFeed      = errors.New("No bookmark feed found")
+	ErrInvalidCategory     = errors.New("Category must contain a list of URLs or categories")
+	ErrInvalidFeedEntry    = errors.New("Feed must be a URL or a mapping with a url key")
+	ErrInvalidUrlsFile     = errors.New("urls.yaml must map categories to feeds")
+	ErrInvalidDuration     = errors.New("Invalid duration, use e.g. 30m, 12h or 7d")
+	ErrInvalidRetention    = errors.New("Retention must be a number of items or a duration")
+	ErrFeedOutsideCategory = errors.New("Feed is not in a category")
+	ErrUnknownKey          = errors.New("Unknown key")
+	ErrInvalidUrl          = errors.New("Invalid URL")
+	ErrDuplicateFeed       = errors.New("Duplicate feed")
+	ErrConflictingHidden   = errors.New("Shared feed must be hidden in every category or none")
+	ErrTabIndent           = errors.New("Line is indented with a tab, use spaces")
+	ErrFeedStatus          = errors.New("Feed didn't answer with 200 OK")
+	ErrFeedContentType     = errors.New("Feed isn't served as XML or JSON")
+	ErrFeedParse           = errors.New("Feed could not be parsed")
+	ErrItemsWithoutGuid    = errors.New("Items have no GUID, read state is tracked by link instead")
+	ErrItemsWithoutDate    = errors.New("Items have no date and can't be sorted")
+	ErrEditingUrls         = errors.New("Could not edit urls.yaml")
+	ErrFlowCategory        = errors.New("Can't add feeds to a category written as [a, b], use one feed per line")
+	ErrCategoryHasChildren = errors.New("Can't add feeds to a category with nested categories")
+	ErrCategoryNotFound    = errors.New("Category not found in urls.yaml")
+	ErrFeedNotFound        = errors.New("Feed not found in urls.yaml")
+	ErrCategoryExists      = errors.New("Category already exists")
+	ErrInvalidCategoryName = errors.New("Category name can't be empty or contain /")
+	ErrInclude             = errors.New("Could not read include")
+	ErrIncludeNotCached    = errors.New("Remote include not cached yet")
+	ErrLocked              = errors.New("Locked by another rssboat")
+	ErrEmptySearch         = errors.New("Search query can't be empty")
+	ErrInvalidInclude      = errors.New("Include must be a path or URL, or a list of them")
+	ErrNestedInclude       = errors.New("Included files can't include others")
+	ErrConfigDoesNotExist  = "open urls.yaml: file does not exist"
+	MsgFeedNotLoaded       = "Feed not loaded yet. Press shift+r"
+	ExampleConfigFile      = `# This file is written in YAML format.
 # Each feed must be organized under a category.
 # Feeds that are not assigned to a category will NOT appear in the app.
 # Formatting Rules:
@@ -25,5 +54,30 @@ var (
 #golang:
 #  - https://go.dev/blog/feed.atom
 #  - https://golang.cafe/rss
+#
+# Tabs appear in the same order as in this file.
+# Categories can be nested, the parent tab shows the feeds of all its children:
+#tech:
+#  go:
+#    - https://go.dev/blog/feed.atom
+#  rust:
+#    - https://blog.rust-lang.org/feed.xml
+#
+# A feed can also be written with options, all but url are optional:
+#news:
+#  - url: https://example.com/feed.xml
+#    title: Example News
+#    tags: [daily]
+#    refresh: 2h
+#    hidden: false
+#    notify: true
+#    retention: 7d
+#    user_agent: Mozilla/5.0
+#
+# Feeds of other files, paths relative to this one or URLs, are added too.
+# Listing an included feed here overrides its options, such as hidden: true.
+#include:
+#  - team.yaml
+#  - https://example.com/team/urls.yaml
 `
 )
@@ -0,0 +1,147 @@
+package rss
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ItemQuery selects items across the list's feeds, zero fields match every item
+type ItemQuery struct {
+	// Category also matches its nested categories
+	Category string
+	// Feed is a feed URL
+	Feed       string
+	Unread     bool
+	Bookmarked bool
+	// Tag matches feeds with the tag in urls.yaml
+	Tag string
+	// Since matches items published or first seen after it
+	Since time.Time
+}
+
+// FeedItem is an item together with the feed it comes from
+type FeedItem struct {
+	Feed *RssFeed
+	Item *RssItem
+}
+
+// Items returns the items matching q, newest first. Hidden feeds are left
+// out unless asked for by URL.
+func (l *List) Items(q ItemQuery) ([]FeedItem, error) {
+	var feeds []*RssFeed
+	switch {
+	case q.Feed != "":
+		feed, ok := l.FeedIndex[q.Feed]
+		if !ok || feed == l.Bookmarks() {
+			return nil, fmt.Errorf("%w: %s", ErrFeedNotFound, q.Feed)
+		}
+		if q.Category != "" && !slices.ContainsFunc(feed.Categories, func(c string) bool {
+			return c == q.Category || isSubcategory(c, q.Category)
+		}) {
+			return nil, nil
+		}
+		feeds = []*RssFeed{feed}
+	case q.Category != "":
+		if !slices.Contains(l.Categories(), q.Category) {
+			return nil, fmt.Errorf("%w: %s", ErrCategoryNotFound, q.Category)
+		}
+		feeds, _ = l.GetCategory(q.Category)
+	default:
+		for _, feed := range l.Feeds {
+			if feed != l.Bookmarks() && !feed.Options.Hidden {
+				feeds = append(feeds, feed)
+			}
+		}
+	}
+
+	var items []FeedItem
+	for _, feed := range feeds {
+		if q.Tag != "" && !slices.Contains(feed.Options.Tags, q.Tag) {
+			continue
+		}
+		for _, item := range feed.RssItems {
+			if q.matches(item) {
+				items = append(items, FeedItem{Feed: feed, Item: item})
+			}
+		}
+	}
+
+	slices.SortStableFunc(items, func(a, b FeedItem) int {
+		return itemDate(b.Item).Compare(itemDate(a.Item))
+	})
+	return items, nil
+}
+
+func (q ItemQuery) matches(item *RssItem) bool {
+	switch {
+	case q.Unread && item.Read:
+		return false
+	case q.Bookmarked && !item.Bookmark:
+		return false
+	case q.Since.IsZero():
+		return true
+	}
+
+	date, seen := itemDate(item), item.FirstSeen
+	return date.After(q.Since) || seen != nil && seen.After(q.Since)
+}
+
+func itemDate(item *RssItem) time.Time {
+	if date := item.Date(); date != nil {
+		return *date
+	}
+	return time.Time{}
+}
+
+// ItemRecord is an item as handed to scripts. Unlike the cache, its fields
+// are kept stable.
+type ItemRecord struct {
+	Feed    string `json:"feed"`
+	FeedUrl string `json:"feed_url"`
+	// Key identifies the item within its feed
+	Key       string     `json:"key"`
+	Category  string     `json:"category"`
+	Title     string     `json:"title"`
+	Link      string     `json:"link"`
+	Author    string     `json:"author,omitempty"`
+	Published *time.Time `json:"published,omitempty"`
+	Updated   *time.Time `json:"updated,omitempty"`
+	FirstSeen *time.Time `json:"first_seen,omitempty"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	Read      bool       `json:"read"`
+	Bookmark  bool       `json:"bookmark"`
+	Content   string     `json:"content,omitempty"`
+}
+
+// Record returns the item as an ItemRecord, with its content when asked for
+func (fi FeedItem) Record(content bool) ItemRecord {
+	r := ItemRecord{
+		Feed:      fi.Feed.Name(),
+		FeedUrl:   fi.Feed.Url,
+		Key:       fi.Item.Key(),
+		Category:  fi.Feed.Category,
+		Link:      fi.Item.Link(),
+		FirstSeen: fi.Item.FirstSeen,
+		ReadAt:    fi.Item.ReadAt,
+		Read:      fi.Item.Read,
+		Bookmark:  fi.Item.Bookmark,
+	}
+
+	if item := fi.Item.Item; item != nil {
+		r.Title = item.Title
+		r.Published = item.PublishedParsed
+		r.Updated = item.UpdatedParsed
+		if item.Author != nil {
+			r.Author = item.Author.Name
+		}
+		if content {
+			r.Content = item.Content
+			if r.Content == "" {
+				r.Content = item.Description
+			}
+		}
+	}
+
+	return r
+}
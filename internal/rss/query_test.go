@@ -0,0 +1,106 @@
+package rss
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestQuery(t *testing.T) {
+	now := time.Now()
+	day := func(n int) *time.Time {
+		d := now.Add(time.Duration(-n) * 24 * time.Hour)
+		return &d
+	}
+
+	newQueryList := func() *List {
+		l := NewListWithDefaults()
+		l.AddFeeds(
+			&RssFeed{Url: "https://example.com/go.xml", Category: "tech/go", RssItems: []*RssItem{
+				{Item: &gofeed.Item{GUID: "old", Title: "Old", PublishedParsed: day(3)}, Read: true},
+				{Item: &gofeed.Item{GUID: "new", Title: "New", PublishedParsed: day(0), Author: &gofeed.Person{Name: "Gopher"}, Content: "<p>Body</p>"}},
+			}},
+			&RssFeed{Url: "https://example.com/news.xml", Category: "news", Options: FeedOptions{Tags: []string{"daily"}}, RssItems: []*RssItem{
+				{Item: &gofeed.Item{GUID: "late", Title: "Late", PublishedParsed: day(5)}, FirstSeen: day(0), Bookmark: true},
+			}},
+			&RssFeed{Url: "https://example.com/hidden.xml", Category: "news", Options: FeedOptions{Hidden: true}, RssItems: []*RssItem{
+				{Item: &gofeed.Item{GUID: "hidden", Title: "Hidden", PublishedParsed: day(1)}},
+			}},
+		)
+		return l
+	}
+
+	titles := func(items []FeedItem) []string {
+		var titles []string
+		for _, i := range items {
+			titles = append(titles, i.Item.Item.Title)
+		}
+		return titles
+	}
+
+	t.Run("Should return items of visible feeds newest first", func(t *testing.T) {
+		items, err := newQueryList().Items(ItemQuery{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if got := titles(items); len(got) != 3 || got[0] != "New" || got[1] != "Old" || got[2] != "Late" {
+			t.Errorf("Wrong items, got %v", got)
+		}
+	})
+
+	t.Run("Should filter by category, state and date", func(t *testing.T) {
+		l := newQueryList()
+
+		items, _ := l.Items(ItemQuery{Category: "tech", Unread: true})
+		if got := titles(items); len(got) != 1 || got[0] != "New" {
+			t.Errorf("Expected unread items of nested categories, got %v", got)
+		}
+
+		items, _ = l.Items(ItemQuery{Since: now.Add(-24 * time.Hour)})
+		if got := titles(items); len(got) != 2 || got[0] != "New" || got[1] != "Late" {
+			t.Errorf("Expected items published or first seen since, got %v", got)
+		}
+
+		items, _ = l.Items(ItemQuery{Bookmarked: true})
+		if got := titles(items); len(got) != 1 || got[0] != "Late" {
+			t.Errorf("Expected bookmarked items, got %v", got)
+		}
+
+		items, _ = l.Items(ItemQuery{Tag: "daily"})
+		if got := titles(items); len(got) != 1 || got[0] != "Late" {
+			t.Errorf("Expected items of tagged feeds, got %v", got)
+		}
+
+		items, _ = l.Items(ItemQuery{Feed: "https://example.com/hidden.xml"})
+		if got := titles(items); len(got) != 1 {
+			t.Errorf("Hidden feed asked for by URL should be listed, got %v", got)
+		}
+	})
+
+	t.Run("Should report unknown categories and feeds", func(t *testing.T) {
+		l := newQueryList()
+		if _, err := l.Items(ItemQuery{Category: "art"}); !errors.Is(err, ErrCategoryNotFound) {
+			t.Errorf("Expected ErrCategoryNotFound, got %v", err)
+		}
+		if _, err := l.Items(ItemQuery{Feed: "https://example.com/none.xml"}); !errors.Is(err, ErrFeedNotFound) {
+			t.Errorf("Expected ErrFeedNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Should build records with content only when asked", func(t *testing.T) {
+		items, _ := newQueryList().Items(ItemQuery{Feed: "https://example.com/go.xml", Unread: true})
+
+		r := items[0].Record(false)
+		if r.Feed != "https://example.com/go.xml" || r.Category != "tech/go" || r.Author != "Gopher" || r.Read {
+			t.Errorf("Wrong record: %+v", r)
+		}
+		if r.Content != "" {
+			t.Error("Content should be left out")
+		}
+		if r = items[0].Record(true); r.Content != "<p>Body</p>" {
+			t.Errorf("Expected content, got %q", r.Content)
+		}
+	})
+}
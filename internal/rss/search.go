@@ -0,0 +1,83 @@
+package rss
+
+import (
+	"slices"
+	"strings"
+)
+
+// How much a word found in each part of an item counts towards its score
+const (
+	titleWeight       = 10
+	descriptionWeight = 3
+	contentWeight     = 1
+	// Added when the whole query is found in the title
+	phraseWeight = 20
+)
+
+// SearchResult is an item found by Search with how well it matches
+type SearchResult struct {
+	FeedItem
+	Score int
+}
+
+// Search returns the items matching q that contain every word of query in
+// their title, description or content, ignoring case. Best matches come
+// first, words found in the title count most. Items that match as well come
+// newest first.
+func (l *List) Search(query string, q ItemQuery) ([]SearchResult, error) {
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 {
+		return nil, ErrEmptySearch
+	}
+
+	items, err := l.Items(q)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+	for _, fi := range items {
+		if score := searchScore(fi.Item, words); score > 0 {
+			results = append(results, SearchResult{FeedItem: fi, Score: score})
+		}
+	}
+
+	slices.SortStableFunc(results, func(a, b SearchResult) int {
+		return b.Score - a.Score
+	})
+	return results, nil
+}
+
+// searchScore scores an item for the words of a query, 0 when a word isn't
+// found. Items are cleaned of HTML when fetched, see sanitizeItem.
+func searchScore(item *RssItem, words []string) int {
+	if item.Item == nil {
+		return 0
+	}
+	title := strings.ToLower(item.Item.Title)
+	description := strings.ToLower(item.Item.Description)
+	content := strings.ToLower(item.Item.Content)
+
+	score := 0
+	for _, word := range words {
+		found := 0
+		if strings.Contains(title, word) {
+			found += titleWeight
+		}
+		if strings.Contains(description, word) {
+			found += descriptionWeight
+		}
+		if strings.Contains(content, word) {
+			found += contentWeight
+		}
+		if found == 0 {
+			return 0
+		}
+		score += found
+	}
+
+	if len(words) > 1 && strings.Contains(title, strings.Join(words, " ")) {
+		score += phraseWeight
+	}
+	return score
+}
@@ -0,0 +1,83 @@
+package rss
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestSearch(t *testing.T) {
+	now := time.Now()
+	day := func(n int) *time.Time {
+		d := now.Add(time.Duration(-n) * 24 * time.Hour)
+		return &d
+	}
+
+	newSearchList := func() *List {
+		l := NewListWithDefaults()
+		l.AddFeeds(
+			&RssFeed{Url: "https://example.com/go.xml", Category: "tech", RssItems: []*RssItem{
+				{Item: &gofeed.Item{GUID: "content", Title: "Release notes", Content: "Generics in Go", PublishedParsed: day(0)}},
+				{Item: &gofeed.Item{GUID: "title", Title: "Go generics explained", PublishedParsed: day(2)}, Read: true},
+				{Item: &gofeed.Item{GUID: "description", Title: "Weekly", Description: "More on generics, and Go", PublishedParsed: day(1)}},
+			}},
+			&RssFeed{Url: "https://example.com/news.xml", Category: "news", RssItems: []*RssItem{
+				{Item: &gofeed.Item{GUID: "other", Title: "Generics elsewhere", PublishedParsed: day(0)}},
+			}},
+		)
+		return l
+	}
+
+	keys := func(results []SearchResult) []string {
+		var keys []string
+		for _, r := range results {
+			keys = append(keys, r.Item.Key())
+		}
+		return keys
+	}
+
+	t.Run("Should rank title matches above description and content", func(t *testing.T) {
+		results, err := newSearchList().Search("GO Generics", ItemQuery{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		got := keys(results)
+		if len(got) != 3 || got[0] != "title" || got[1] != "description" || got[2] != "content" {
+			t.Errorf("Wrong ranking, got %v", got)
+		}
+		if results[0].Score <= results[1].Score {
+			t.Errorf("Whole query in the title should score highest, got %d and %d", results[0].Score, results[1].Score)
+		}
+	})
+
+	t.Run("Should put equal matches newest first", func(t *testing.T) {
+		results, _ := newSearchList().Search("generics", ItemQuery{})
+
+		got := keys(results)
+		if len(got) != 4 || got[0] != "other" || got[1] != "title" {
+			t.Errorf("Expected title matches newest first, got %v", got)
+		}
+	})
+
+	t.Run("Should search within the query", func(t *testing.T) {
+		l := newSearchList()
+
+		results, _ := l.Search("generics", ItemQuery{Category: "tech", Unread: true})
+		if got := keys(results); len(got) != 2 || got[0] != "description" || got[1] != "content" {
+			t.Errorf("Expected unread tech items, got %v", got)
+		}
+
+		if _, err := l.Search("generics", ItemQuery{Category: "none"}); !errors.Is(err, ErrCategoryNotFound) {
+			t.Errorf("Expected ErrCategoryNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Should not search for nothing", func(t *testing.T) {
+		if _, err := newSearchList().Search("  ", ItemQuery{}); !errors.Is(err, ErrEmptySearch) {
+			t.Errorf("Expected ErrEmptySearch, got %v", err)
+		}
+	})
+}
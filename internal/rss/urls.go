@@ -0,0 +1,468 @@
+package rss
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	yaml "github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// FeedOptions are the optional settings of a urls.yaml entry written as a mapping:
+//
+//   - url: https://example.com/feed.xml
+//     title: Example
+//     refresh: 2h
+type FeedOptions struct {
+	// Title replaces the title from the feed
+	Title string
+	Tags  []string
+	// Refresh is the minimum time between refreshes of all feeds
+	Refresh time.Duration
+	// Hidden feeds are neither shown nor refreshed. A feed listed in several
+	// categories is one feed, so it must be hidden in all of them or none.
+	Hidden bool
+	// Notify sends a desktop notification when new items arrive
+	Notify    bool
+	Retention Retention
+	UserAgent string
+}
+
+// Retention limits how many read items a feed keeps. Unread and bookmarked
+// items are always kept.
+type Retention struct {
+	// Age drops read items older than this
+	Age time.Duration
+	// Count keeps at most this many read items
+	Count int
+}
+
+var feedEntryKeys = []string{"url", "title", "tags", "refresh", "hidden", "notify", "retention", "user_agent"}
+
+// ConfigError is a problem in a config file, at a given line when known
+type ConfigError struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e *ConfigError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %v", e.File, e.Err)
+	}
+	return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+type urlsParser struct {
+	filename string
+	feeds    []*RssFeed
+	// lint also checks URLs and duplicates, which loading tolerates
+	lint bool
+	// problems found by lint, which don't stop the file loading
+	warnings []*ConfigError
+	// included files can't include others
+	included bool
+	includes []include
+	// lines of the feeds seen so far by category and URL
+	lines map[string]int
+	// first entry of each URL, to check hidden is the same in every category
+	firsts   map[string]firstEntry
+	problems []*ConfigError
+}
+
+type firstEntry struct {
+	line   int
+	hidden bool
+}
+
+// parseUrls reads feeds from urls.yaml in file order
+func parseUrls(filename string, data []byte) ([]*RssFeed, error) {
+	p := &urlsParser{filename: filename}
+	if err := p.parse(data); err != nil {
+		return nil, err
+	}
+	if len(p.problems) > 0 {
+		return nil, p.problems[0]
+	}
+	return p.feeds, nil
+}
+
+// parse reads the whole file, collecting problems instead of stopping at the
+// first one. Only invalid YAML stops it.
+func (p *urlsParser) parse(data []byte) error {
+	file, err := parser.ParseBytes(data, 0)
+	if err != nil {
+		if tabs := tabIndents(p.filename, data); len(tabs) > 0 {
+			return tabs[0]
+		}
+		return yamlError(p.filename, err)
+	}
+
+	for _, doc := range file.Docs {
+		if isEmpty(doc.Body) {
+			continue
+		}
+
+		if seq, ok := doc.Body.(*ast.SequenceNode); ok {
+			for _, node := range seq.Values {
+				p.problem(node, "%w", ErrFeedOutsideCategory)
+			}
+			continue
+		}
+
+		values, ok := mappingValues(doc.Body)
+		if !ok {
+			p.problem(doc.Body, "%w", ErrInvalidUrlsFile)
+			continue
+		}
+		p.categories("", values)
+	}
+
+	return nil
+}
+
+func (p *urlsParser) categories(parent string, values []*ast.MappingValueNode) {
+	for _, mv := range values {
+		category := keyName(mv.Key)
+		if parent == "" && category == includeKey {
+			p.include(mv)
+			continue
+		}
+		if parent != "" {
+			category = parent + CategorySeparator + category
+		}
+
+		if isEmpty(mv.Value) {
+			continue
+		}
+
+		if nested, ok := mappingValues(mv.Value); ok {
+			p.categories(category, nested)
+			continue
+		}
+
+		seq, ok := mv.Value.(*ast.SequenceNode)
+		if !ok {
+			p.problem(mv.Value, "%w: %s", ErrInvalidCategory, category)
+			continue
+		}
+
+		for _, node := range seq.Values {
+			feed := p.entry(node)
+			if feed == nil {
+				continue
+			}
+			feed.Category = category
+			if p.lint {
+				p.check(node, feed)
+			}
+			p.feeds = append(p.feeds, feed)
+		}
+	}
+}
+
+// include reads the files listed under include:, a single one or a list
+func (p *urlsParser) include(mv *ast.MappingValueNode) {
+	if p.included {
+		p.problem(mv.Key, "%w", ErrNestedInclude)
+		return
+	}
+
+	nodes := []ast.Node{mv.Value}
+	if seq, ok := mv.Value.(*ast.SequenceNode); ok {
+		nodes = seq.Values
+	}
+	for _, node := range nodes {
+		s, ok := node.(*ast.StringNode)
+		if !ok || s.Value == "" {
+			p.problem(node, "%w", ErrInvalidInclude)
+			continue
+		}
+		p.includes = append(p.includes, include{source: s.Value, line: node.GetToken().Position.Line})
+	}
+}
+
+// entry reads a feed written either as a bare URL or as a mapping with
+// options. It returns nil when the entry has no usable URL.
+func (p *urlsParser) entry(node ast.Node) *RssFeed {
+	if s, ok := node.(*ast.StringNode); ok {
+		return &RssFeed{Url: s.Value}
+	}
+
+	values, ok := mappingValues(node)
+	if !ok {
+		p.problem(node, "%w", ErrInvalidFeedEntry)
+		return nil
+	}
+
+	feed := &RssFeed{}
+	for _, mv := range values {
+		key := keyName(mv.Key)
+
+		switch key {
+		case "url":
+			p.decode(mv.Value, &feed.Url)
+		case "title":
+			p.decode(mv.Value, &feed.Options.Title)
+		case "tags":
+			p.decode(mv.Value, &feed.Options.Tags)
+		case "hidden":
+			p.decode(mv.Value, &feed.Options.Hidden)
+		case "notify":
+			p.decode(mv.Value, &feed.Options.Notify)
+		case "user_agent":
+			p.decode(mv.Value, &feed.Options.UserAgent)
+		case "refresh":
+			feed.Options.Refresh = p.duration(mv.Value)
+		case "retention":
+			feed.Options.Retention = p.retention(mv.Value)
+		default:
+			p.problem(mv.Key, "%w %q, expected one of %s", ErrUnknownKey, key, strings.Join(feedEntryKeys, ", "))
+		}
+	}
+
+	if feed.Url == "" {
+		p.problem(node, "%w", ErrFeedHasNoUrl)
+		return nil
+	}
+
+	return feed
+}
+
+// check reports URLs that can't be fetched, feeds listed twice in a
+// category and shared feeds hidden in only some of their categories. The
+// same URL in different categories is a shared feed.
+func (p *urlsParser) check(node ast.Node, feed *RssFeed) {
+	if _, err := url.ParseRequestURI(feed.Url); err != nil {
+		p.warn(node, "%w: %s", ErrInvalidUrl, feed.Url)
+	}
+
+	if p.lines == nil {
+		p.lines = make(map[string]int)
+		p.firsts = make(map[string]firstEntry)
+	}
+	line := node.GetToken().Position.Line
+	if first, ok := p.firsts[feed.Url]; !ok {
+		p.firsts[feed.Url] = firstEntry{line: line, hidden: feed.Options.Hidden}
+	} else if first.hidden != feed.Options.Hidden {
+		p.warn(node, "%w: %s is listed on line %d", ErrConflictingHidden, feed.Url, first.line)
+	}
+
+	key := feed.Category + "\n" + feed.Url
+	if first, ok := p.lines[key]; ok {
+		p.warn(node, "%w: %s is already listed on line %d", ErrDuplicateFeed, feed.Url, first)
+		return
+	}
+	p.lines[key] = line
+}
+
+func (p *urlsParser) decode(node ast.Node, v any) bool {
+	if err := yaml.NodeToValue(node, v); err != nil {
+		p.problem(node, "invalid value %s", node.String())
+		return false
+	}
+	return true
+}
+
+func (p *urlsParser) scalar(node ast.Node) (string, bool) {
+	var raw any
+	if !p.decode(node, &raw) {
+		return "", false
+	}
+	switch raw.(type) {
+	case []any, map[string]any, nil:
+		p.problem(node, "invalid value %s", node.String())
+		return "", false
+	}
+	return fmt.Sprint(raw), true
+}
+
+func (p *urlsParser) duration(node ast.Node) time.Duration {
+	raw, ok := p.scalar(node)
+	if !ok {
+		return 0
+	}
+
+	d, err := ParseDuration(raw)
+	if err != nil {
+		p.problem(node, "%w", err)
+	}
+	return d
+}
+
+// retention is either a number of items or a maximum age
+func (p *urlsParser) retention(node ast.Node) Retention {
+	raw, ok := p.scalar(node)
+	if !ok {
+		return Retention{}
+	}
+
+	if count, err := strconv.Atoi(raw); err == nil && count > 0 {
+		return Retention{Count: count}
+	}
+
+	d, err := ParseDuration(raw)
+	if err != nil {
+		p.problem(node, "%w", ErrInvalidRetention)
+		return Retention{}
+	}
+	return Retention{Age: d}
+}
+
+func (p *urlsParser) problem(node ast.Node, format string, args ...any) {
+	p.problems = append(p.problems, p.configError(node, format, args...))
+}
+
+func (p *urlsParser) warn(node ast.Node, format string, args ...any) {
+	p.warnings = append(p.warnings, p.configError(node, format, args...))
+}
+
+func (p *urlsParser) configError(node ast.Node, format string, args ...any) *ConfigError {
+	return &ConfigError{
+		File: p.filename,
+		Line: node.GetToken().Position.Line,
+		Err:  fmt.Errorf(format, args...),
+	}
+}
+
+// tabIndents finds lines indented with tabs, which YAML doesn't allow
+func tabIndents(filename string, data []byte) []*ConfigError {
+	var problems []*ConfigError
+	for i, line := range strings.Split(string(data), "\n") {
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if strings.Contains(indent, "\t") {
+			problems = append(problems, &ConfigError{File: filename, Line: i + 1, Err: ErrTabIndent})
+		}
+	}
+	return problems
+}
+
+func yamlError(filename string, err error) error {
+	var yamlErr yaml.Error
+	if errors.As(err, &yamlErr) && yamlErr.GetToken() != nil {
+		return &ConfigError{
+			File: filename,
+			Line: yamlErr.GetToken().Position.Line,
+			Err:  errors.New(yamlErr.GetMessage()),
+		}
+	}
+	return err
+}
+
+func keyName(key ast.MapKeyNode) string {
+	if s, ok := key.(*ast.StringNode); ok {
+		return s.Value
+	}
+	return key.String()
+}
+
+func mappingValues(node ast.Node) ([]*ast.MappingValueNode, bool) {
+	switch n := node.(type) {
+	case *ast.MappingNode:
+		return n.Values, true
+	case *ast.MappingValueNode:
+		return []*ast.MappingValueNode{n}, true
+	}
+	return nil, false
+}
+
+func isEmpty(node ast.Node) bool {
+	switch node.(type) {
+	case nil, *ast.NullNode, *ast.CommentGroupNode:
+		return true
+	}
+	return false
+}
+
+// ParseDuration extends time.ParseDuration with days and weeks, such as "7d" or "2w"
+func ParseDuration(s string) (time.Duration, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.ParseFloat(n, 64)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("%w: %q", ErrInvalidDuration, s)
+			}
+			return time.Duration(count * float64(unit)), nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidDuration, s)
+	}
+	return d, nil
+}
+
+// merge fills options left unset from another entry for the same feed, and
+// combines their tags
+func (o *FeedOptions) merge(other FeedOptions) {
+	for _, tag := range other.Tags {
+		if !slices.Contains(o.Tags, tag) {
+			o.Tags = append(o.Tags, tag)
+		}
+	}
+	if o.Title == "" {
+		o.Title = other.Title
+	}
+	if o.Refresh == 0 {
+		o.Refresh = other.Refresh
+	}
+	if o.Retention == (Retention{}) {
+		o.Retention = other.Retention
+	}
+	if o.UserAgent == "" {
+		o.UserAgent = other.UserAgent
+	}
+	o.Hidden = o.Hidden || other.Hidden
+	o.Notify = o.Notify || other.Notify
+}
+
+// keep reports whether retention allows keeping a read item at the given position
+func (r Retention) keep(item *RssItem, readIndex int, now time.Time) bool {
+	if r.Count > 0 && readIndex >= r.Count {
+		return false
+	}
+	if r.Age > 0 {
+		if date := item.Date(); date != nil && now.Sub(*date) > r.Age {
+			return false
+		}
+	}
+	return true
+}
+
+// applyRetention drops read items the feed's retention doesn't allow
+// keeping, remembering their keys in Dropped
+func (f *RssFeed) applyRetention() {
+	r := f.Options.Retention
+	if r == (Retention{}) {
+		return
+	}
+
+	f.remember(f.RssItems)
+	now := time.Now()
+	read := 0
+	f.RssItems = slices.DeleteFunc(f.RssItems, func(item *RssItem) bool {
+		if !item.Read || item.Bookmark {
+			return false
+		}
+		read++
+		if r.keep(item, read-1, now) {
+			return false
+		}
+		if key := item.Key(); key != "" {
+			f.Dropped = append(f.Dropped, key)
+		}
+		return true
+	})
+}
@@ -0,0 +1,396 @@
+package rss
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	yaml "github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// AddToUrls adds feeds to the content of urls.yaml, keeping its comments and
+// layout. Feeds whose URL is already in the file are skipped. It returns the
+// new content and the feeds that were added.
+func AddToUrls(filename string, data []byte, feeds []*RssFeed) ([]byte, []*RssFeed, error) {
+	existing, err := parseUrls(filename, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	known := make(map[string]bool)
+	for _, f := range existing {
+		known[f.Url] = true
+	}
+
+	var (
+		added      []*RssFeed
+		categories []string
+		groups     = make(map[string][]*RssFeed)
+		seen       = make(map[string]bool)
+	)
+	for _, f := range feeds {
+		key := f.Category + "\n" + f.Url
+		if known[f.Url] || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if _, ok := groups[f.Category]; !ok {
+			categories = append(categories, f.Category)
+		}
+		groups[f.Category] = append(groups[f.Category], f)
+		added = append(added, f)
+	}
+
+	for _, category := range categories {
+		group := groups[category]
+		data, err = addCategoryEntries(filename, data, category, func(indent int) []string {
+			var lines []string
+			for _, f := range group {
+				lines = append(lines, feedEntry(f, indent)...)
+			}
+			return lines
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if _, err := parseUrls(filename, data); err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrEditingUrls, err)
+	}
+
+	return data, added, nil
+}
+
+// addCategoryEntries inserts entries at the end of a category, creating the
+// category or the missing part of a nested one. When a category already has
+// feeds, nested categories below it can't be added and their feeds go to it.
+// entries returns the lines to insert at the given indentation.
+func addCategoryEntries(filename string, data []byte, category string, entries func(indent int) []string) ([]byte, error) {
+	file, err := parser.ParseBytes(data, parser.ParseComments)
+	if err != nil {
+		return nil, yamlError(filename, err)
+	}
+
+	var values []*ast.MappingValueNode
+	for _, doc := range file.Docs {
+		if v, ok := mappingValues(doc.Body); ok {
+			values = v
+			break
+		}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(data) == 0 {
+		lines = nil
+	}
+
+	// Without a matching category the feeds go at the end of the file
+	at, indent := len(lines), 0
+	var missing []string
+
+	parts := strings.Split(category, CategorySeparator)
+	for i, part := range parts {
+		mv := findKey(values, part)
+		if mv == nil {
+			missing = parts[i:]
+			if i > 0 {
+				at, indent = endLine(values...), column(values[0].Key)
+			}
+			break
+		}
+
+		switch value := mv.Value.(type) {
+		case *ast.SequenceNode:
+			if value.IsFlowStyle {
+				return nil, fmt.Errorf("%w: %s", ErrFlowCategory, strings.Join(parts[:i+1], CategorySeparator))
+			}
+			at, indent = endLine(value), column(value)
+		case *ast.MappingNode, *ast.MappingValueNode:
+			nested, _ := mappingValues(value)
+			if i == len(parts)-1 {
+				return nil, fmt.Errorf("%w: %s", ErrCategoryHasChildren, category)
+			}
+			values = nested
+			continue
+		default:
+			// An empty category
+			at, indent = mv.Key.GetToken().Position.Line, column(mv.Key)+2
+		}
+		break
+	}
+
+	var block []string
+	for _, part := range missing {
+		block = append(block, strings.Repeat(" ", indent)+yamlScalar(part)+":")
+		indent += 2
+	}
+	block = append(block, entries(indent)...)
+
+	lines = append(lines[:at], append(block, lines[at:]...)...)
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
+func feedEntry(f *RssFeed, indent int) []string {
+	pad := strings.Repeat(" ", indent)
+	if f.Options.Title == "" && !f.Options.Hidden {
+		return []string{pad + "- " + yamlScalar(f.Url)}
+	}
+
+	lines := []string{pad + "- url: " + yamlScalar(f.Url)}
+	if f.Options.Title != "" {
+		lines = append(lines, pad+"  title: "+yamlScalar(f.Options.Title))
+	}
+	if f.Options.Hidden {
+		lines = append(lines, pad+"  hidden: true")
+	}
+	return lines
+}
+
+// yamlScalar quotes a string when YAML needs it
+func yamlScalar(s string) string {
+	out, err := yaml.Marshal(s)
+	if err != nil {
+		return fmt.Sprintf("%q", s)
+	}
+	return strings.TrimSuffix(string(out), "\n")
+}
+
+func findKey(values []*ast.MappingValueNode, key string) *ast.MappingValueNode {
+	for _, mv := range values {
+		if keyName(mv.Key) == key {
+			return mv
+		}
+	}
+	return nil
+}
+
+// column is the zero based column a node starts at
+func column(node ast.Node) int {
+	return node.GetToken().Position.Column - 1
+}
+
+// endLine is the last line holding any part of the nodes
+func endLine[T ast.Node](nodes ...T) int {
+	v := &lastLine{}
+	for _, node := range nodes {
+		ast.Walk(v, node)
+	}
+	return v.line
+}
+
+type lastLine struct {
+	line int
+}
+
+func (v *lastLine) Visit(node ast.Node) ast.Visitor {
+	if tk := node.GetToken(); tk != nil && tk.Position.Line > v.line {
+		v.line = tk.Position.Line
+	}
+	return v
+}
+
+// RemoveFromUrls removes a feed from a category of urls.yaml, keeping the
+// rest of the file as it is
+func RemoveFromUrls(filename string, data []byte, category, url string) ([]byte, error) {
+	start, end, _, err := entryLines(filename, data, category, url)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(string(data), "\n")
+	lines = append(lines[:start-1], lines[end:]...)
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// MoveInUrls moves a feed's entry, with its options and comments, from one
+// category to the end of another, creating the category if needed
+func MoveInUrls(filename string, data []byte, url, from, to string) ([]byte, error) {
+	if from == to {
+		return data, nil
+	}
+
+	feeds, err := parseUrls(filename, data)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range feeds {
+		if f.Url == url && f.Category == to {
+			return nil, fmt.Errorf("%w: %s is already in %s", ErrDuplicateFeed, url, to)
+		}
+	}
+
+	start, end, indent, err := entryLines(filename, data, from, url)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(string(data), "\n")
+	entry := slices.Clone(lines[start-1 : end])
+	for i, line := range entry {
+		entry[i] = line[min(indent, len(line)-len(strings.TrimLeft(line, " "))):]
+	}
+
+	data = []byte(strings.Join(append(lines[:start-1], lines[end:]...), "\n"))
+	return addCategoryEntries(filename, data, to, func(indent int) []string {
+		moved := make([]string, len(entry))
+		for i, line := range entry {
+			moved[i] = strings.Repeat(" ", indent) + line
+		}
+		return moved
+	})
+}
+
+// RenameCategory renames the last part of a category, keeping its place,
+// feeds and comments
+func RenameCategory(filename string, data []byte, category, name string) ([]byte, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.Contains(name, CategorySeparator) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidCategoryName, name)
+	}
+
+	file, err := parser.ParseBytes(data, 0)
+	if err != nil {
+		return nil, yamlError(filename, err)
+	}
+
+	mv := findCategory(file, category)
+	if mv == nil {
+		return nil, fmt.Errorf("%w: %s", ErrCategoryNotFound, category)
+	}
+	renamed := name
+	if parent := ParentCategory(category); parent != "" {
+		renamed = parent + CategorySeparator + name
+	}
+	if renamed != category && findCategory(file, renamed) != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCategoryExists, renamed)
+	}
+
+	lines := strings.Split(string(data), "\n")
+	pos := mv.Key.GetToken().Position
+	line := lines[pos.Line-1]
+	start := pos.Column - 1
+	end := keyEnd(line, start)
+	lines[pos.Line-1] = line[:start] + yamlScalar(name) + line[end:]
+
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// keyEnd finds where a mapping key starting at start ends, at its colon
+func keyEnd(line string, start int) int {
+	i := start
+	if quote := line[i]; quote == '"' || quote == '\'' {
+		for i++; i < len(line) && line[i] != quote; i++ {
+			if line[i] == '\\' && quote == '"' {
+				i++
+			}
+		}
+	}
+	for ; i < len(line); i++ {
+		if line[i] == ':' && (i+1 == len(line) || line[i+1] == ' ') {
+			return i
+		}
+	}
+	return len(line)
+}
+
+// findCategory returns the key holding a category, nil if there's none
+func findCategory(file *ast.File, category string) *ast.MappingValueNode {
+	var values []*ast.MappingValueNode
+	for _, doc := range file.Docs {
+		if v, ok := mappingValues(doc.Body); ok {
+			values = v
+			break
+		}
+	}
+
+	var mv *ast.MappingValueNode
+	for _, part := range strings.Split(category, CategorySeparator) {
+		if mv = findKey(values, part); mv == nil {
+			return nil
+		}
+		values, _ = mappingValues(mv.Value)
+	}
+	return mv
+}
+
+// entryLines finds the first and last line of the entry for url in category,
+// and the column of its dash
+func entryLines(filename string, data []byte, category, url string) (start, end, indent int, err error) {
+	file, err := parser.ParseBytes(data, 0)
+	if err != nil {
+		return 0, 0, 0, yamlError(filename, err)
+	}
+
+	mv := findCategory(file, category)
+	if mv == nil {
+		return 0, 0, 0, fmt.Errorf("%w: %s", ErrCategoryNotFound, category)
+	}
+	seq, ok := mv.Value.(*ast.SequenceNode)
+	if ok && seq.IsFlowStyle {
+		return 0, 0, 0, fmt.Errorf("%w: %s", ErrFlowCategory, category)
+	}
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("%w: %s in %s", ErrFeedNotFound, url, category)
+	}
+
+	lines := strings.Split(string(data), "\n")
+	p := &urlsParser{filename: filename}
+	for _, node := range seq.Values {
+		if f := p.entry(node); f == nil || f.Url != url {
+			continue
+		}
+
+		start = node.GetToken().Position.Line
+		if !strings.HasPrefix(strings.TrimSpace(lines[start-1]), "-") {
+			// The dash is on a line of its own
+			start--
+		}
+		line := lines[start-1]
+		return start, endLine(node), len(line) - len(strings.TrimLeft(line, " ")), nil
+	}
+
+	return 0, 0, 0, fmt.Errorf("%w: %s in %s", ErrFeedNotFound, url, category)
+}
+
+// AddToUrlsFile adds feeds to the urls.yaml file at path, see AddToUrls
+func AddToUrlsFile(path string, feeds []*RssFeed) ([]*RssFeed, error) {
+	var added []*RssFeed
+	err := EditUrlsFile(path, func(filename string, data []byte) ([]byte, error) {
+		out, feeds, err := AddToUrls(filename, data, feeds)
+		added = feeds
+		return out, err
+	})
+	return added, err
+}
+
+// EditUrlsFile applies an edit to the urls.yaml file at path. The file is
+// only written when the edited content still loads.
+func EditUrlsFile(path string, edit func(filename string, data []byte) ([]byte, error)) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	filename := filepath.Base(path)
+	out, err := edit(filename, data)
+	if err != nil || bytes.Equal(out, data) {
+		return err
+	}
+	if _, err := parseUrls(filename, out); err != nil {
+		return fmt.Errorf("%w: %w", ErrEditingUrls, err)
+	}
+
+	// Write next to the file and rename, so a failed write can't lose feeds
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, out, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
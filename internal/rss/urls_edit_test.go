@@ -0,0 +1,213 @@
+package rss
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestUrlsEdit(t *testing.T) {
+	t.Run("Should add feeds keeping comments and skipping known URLs", func(t *testing.T) {
+		data := []byte(`# My feeds
+news:
+  - https://example.com/news.xml # daily
+  # - https://example.com/old.xml
+
+tech:
+  go:
+    - https://go.dev/blog/feed.atom
+`)
+
+		out, added, err := AddToUrls("urls.yaml", data, []*RssFeed{
+			{Url: "https://example.com/news.xml", Category: "news"},
+			{Url: "https://example.com/world.xml", Category: "news", Options: FeedOptions{Title: "World: News"}},
+			{Url: "https://blog.rust-lang.org/feed.xml", Category: "tech/rust"},
+			{Url: "https://example.com/art.xml", Category: "art/design"},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		want := `# My feeds
+news:
+  - https://example.com/news.xml # daily
+  - url: https://example.com/world.xml
+    title: "World: News"
+  # - https://example.com/old.xml
+
+tech:
+  go:
+    - https://go.dev/blog/feed.atom
+  rust:
+    - https://blog.rust-lang.org/feed.xml
+art:
+  design:
+    - https://example.com/art.xml
+`
+		if string(out) != want {
+			t.Errorf("got:\n%s\nwant:\n%s", out, want)
+		}
+		if len(added) != 3 {
+			t.Errorf("Known URL should be skipped, got %d added", len(added))
+		}
+	})
+
+	t.Run("Should add to empty files and empty categories", func(t *testing.T) {
+		out, _, err := AddToUrls("urls.yaml", []byte("# nothing yet\nnews:\n"), []*RssFeed{
+			{Url: "https://example.com/news.xml", Category: "news"},
+			{Url: "https://example.com/tech.xml", Category: "tech", Options: FeedOptions{Hidden: true}},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		want := "# nothing yet\nnews:\n  - https://example.com/news.xml\ntech:\n  - url: https://example.com/tech.xml\n    hidden: true\n"
+		if string(out) != want {
+			t.Errorf("got:\n%s\nwant:\n%s", out, want)
+		}
+	})
+
+	t.Run("Should add nested feeds to a parent that has feeds", func(t *testing.T) {
+		out, _, err := AddToUrls("urls.yaml", []byte("tech:\n  - https://example.com/a.xml\n"), []*RssFeed{
+			{Url: "https://example.com/b.xml", Category: "tech/go"},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		want := "tech:\n  - https://example.com/a.xml\n  - https://example.com/b.xml\n"
+		if string(out) != want {
+			t.Errorf("got:\n%s\nwant:\n%s", out, want)
+		}
+	})
+
+	t.Run("Should refuse categories it can't add to", func(t *testing.T) {
+		_, _, err := AddToUrls("urls.yaml", []byte("tech: [https://example.com/a.xml]\n"), []*RssFeed{
+			{Url: "https://example.com/b.xml", Category: "tech"},
+		})
+		if !errors.Is(err, ErrFlowCategory) {
+			t.Errorf("Expected ErrFlowCategory, got %v", err)
+		}
+
+		_, _, err = AddToUrls("urls.yaml", []byte("tech:\n  go:\n    - https://example.com/a.xml\n"), []*RssFeed{
+			{Url: "https://example.com/b.xml", Category: "tech"},
+		})
+		if !errors.Is(err, ErrCategoryHasChildren) {
+			t.Errorf("Expected ErrCategoryHasChildren, got %v", err)
+		}
+	})
+
+	t.Run("Should remove a feed from a category", func(t *testing.T) {
+		data := []byte(`news:
+  - https://example.com/a.xml
+  - url: https://example.com/b.xml # daily
+    title: B
+  # keep me
+  - https://example.com/c.xml
+tech:
+  - https://example.com/b.xml
+`)
+
+		out, err := RemoveFromUrls("urls.yaml", data, "news", "https://example.com/b.xml")
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		want := `news:
+  - https://example.com/a.xml
+  # keep me
+  - https://example.com/c.xml
+tech:
+  - https://example.com/b.xml
+`
+		if string(out) != want {
+			t.Errorf("got:\n%s\nwant:\n%s", out, want)
+		}
+
+		_, err = RemoveFromUrls("urls.yaml", data, "art", "https://example.com/b.xml")
+		if !errors.Is(err, ErrCategoryNotFound) {
+			t.Errorf("Expected ErrCategoryNotFound, got %v", err)
+		}
+		_, err = RemoveFromUrls("urls.yaml", data, "tech", "https://example.com/a.xml")
+		if !errors.Is(err, ErrFeedNotFound) {
+			t.Errorf("Expected ErrFeedNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Should move a feed with its options", func(t *testing.T) {
+		data := []byte(`news:
+  - url: https://example.com/b.xml
+    title: B # mine
+  - https://example.com/a.xml
+tech:
+  go:
+    - https://go.dev/blog/feed.atom
+`)
+
+		out, err := MoveInUrls("urls.yaml", data, "https://example.com/b.xml", "news", "tech/go")
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		want := `news:
+  - https://example.com/a.xml
+tech:
+  go:
+    - https://go.dev/blog/feed.atom
+    - url: https://example.com/b.xml
+      title: B # mine
+`
+		if string(out) != want {
+			t.Errorf("got:\n%s\nwant:\n%s", out, want)
+		}
+
+		out, err = MoveInUrls("urls.yaml", data, "https://example.com/a.xml", "news", "art")
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if !strings.HasSuffix(string(out), "art:\n  - https://example.com/a.xml\n") {
+			t.Errorf("Should create the category, got:\n%s", out)
+		}
+	})
+
+	t.Run("Should rename a category in place", func(t *testing.T) {
+		data := []byte(`news: # world
+  - https://example.com/a.xml
+tech:
+  "go lang":
+    - https://go.dev/blog/feed.atom
+  rust:
+    - https://blog.rust-lang.org/feed.xml
+`)
+
+		out, err := RenameCategory("urls.yaml", data, "tech/go lang", "golang")
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		out, err = RenameCategory("urls.yaml", out, "news", "World: News")
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		want := `"World: News": # world
+  - https://example.com/a.xml
+tech:
+  golang:
+    - https://go.dev/blog/feed.atom
+  rust:
+    - https://blog.rust-lang.org/feed.xml
+`
+		if string(out) != want {
+			t.Errorf("got:\n%s\nwant:\n%s", out, want)
+		}
+
+		_, err = RenameCategory("urls.yaml", data, "tech/rust", "go lang")
+		if !errors.Is(err, ErrCategoryExists) {
+			t.Errorf("Expected ErrCategoryExists, got %v", err)
+		}
+		_, err = RenameCategory("urls.yaml", data, "news", "a/b")
+		if !errors.Is(err, ErrInvalidCategoryName) {
+			t.Errorf("Expected ErrInvalidCategoryName, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,220 @@
+package rss
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func loadUrls(t *testing.T, yaml string) (*List, error) {
+	t.Helper()
+	l := NewListWithDefaults()
+	fs := fstest.MapFS{"urls.yaml": {Data: []byte(yaml)}}
+	return l, l.CreateFeedsFromYaml(fs, "urls.yaml")
+}
+
+func TestUrls(t *testing.T) {
+	t.Run("Should read feed options from mapping entries", func(t *testing.T) {
+		l, err := loadUrls(t, `news:
+  - https://example.com/plain.xml
+  - url: https://example.com/feed.xml
+    title: Example
+    tags: [daily, world]
+    refresh: 2h
+    notify: true
+    retention: 7d
+    user_agent: Test/1.0
+`)
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		if l.FeedIndex["https://example.com/plain.xml"] == nil {
+			t.Error("Plain URL entries should still work")
+		}
+
+		o := l.FeedIndex["https://example.com/feed.xml"].Options
+		if o.Title != "Example" || !slices.Equal(o.Tags, []string{"daily", "world"}) {
+			t.Errorf("Wrong title or tags: %+v", o)
+		}
+		if o.Refresh != 2*time.Hour || !o.Notify || o.UserAgent != "Test/1.0" {
+			t.Errorf("Wrong options: %+v", o)
+		}
+		if o.Retention != (Retention{Age: 7 * 24 * time.Hour}) {
+			t.Errorf("Wrong retention: %+v", o.Retention)
+		}
+	})
+
+	t.Run("Should report unknown keys with their line", func(t *testing.T) {
+		_, err := loadUrls(t, `news:
+  - url: https://example.com/feed.xml
+    refesh: 2h
+`)
+
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) {
+			t.Fatalf("Expected ConfigError, got %v", err)
+		}
+		if configErr.Line != 3 {
+			t.Errorf("Wrong line, got %d", configErr.Line)
+		}
+	})
+
+	t.Run("Should reject invalid durations", func(t *testing.T) {
+		_, err := loadUrls(t, "news:\n  - url: https://example.com/feed.xml\n    refresh: soon\n")
+		if !errors.Is(err, ErrInvalidDuration) {
+			t.Errorf("Expected ErrInvalidDuration, got %v", err)
+		}
+	})
+
+	t.Run("Should require a url", func(t *testing.T) {
+		_, err := loadUrls(t, "news:\n  - title: Example\n")
+		if !errors.Is(err, ErrFeedHasNoUrl) {
+			t.Errorf("Expected ErrFeedHasNoUrl, got %v", err)
+		}
+	})
+
+	t.Run("Should leave hidden feeds out of tabs and refreshes", func(t *testing.T) {
+		l, err := loadUrls(t, `news:
+  - https://example.com/shown.xml
+  - url: https://example.com/hidden.xml
+    hidden: true
+`)
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		feeds, _ := l.GetCategory("news")
+		if len(feeds) != 1 || feeds[0].Url != "https://example.com/shown.xml" {
+			t.Error("Hidden feed should not be in its tab")
+		}
+		if l.FeedIndex["https://example.com/hidden.xml"].Due() {
+			t.Error("Hidden feed should never be due")
+		}
+	})
+
+	t.Run("Should only refresh feeds whose interval has passed", func(t *testing.T) {
+		recent := time.Now().Add(-time.Hour)
+		old := time.Now().Add(-3 * time.Hour)
+
+		f := RssFeed{Options: FeedOptions{Refresh: 2 * time.Hour}}
+		if !f.Due() {
+			t.Error("Feed never fetched should be due")
+		}
+		f.FetchedAt = &recent
+		if f.Due() {
+			t.Error("Feed fetched within its interval should not be due")
+		}
+		f.FetchedAt = &old
+		if !f.Due() {
+			t.Error("Feed fetched before its interval should be due")
+		}
+	})
+
+	t.Run("Should use title from options", func(t *testing.T) {
+		f := RssFeed{Url: "example.com", Feed: &gofeed.Feed{Title: "Feed"}, Options: FeedOptions{Title: "Mine"}}
+		if f.Name() != "Mine" {
+			t.Errorf("Wrong name, got %s", f.Name())
+		}
+	})
+
+	t.Run("Should drop read items beyond retention", func(t *testing.T) {
+		old := time.Now().Add(-48 * time.Hour)
+		unread := &RssItem{Item: &gofeed.Item{PublishedParsed: &old}}
+		bookmarked := &RssItem{Item: &gofeed.Item{PublishedParsed: &old}, Read: true, Bookmark: true}
+		recent := &RssItem{Item: &gofeed.Item{}, Read: true}
+		stale := &RssItem{Item: &gofeed.Item{PublishedParsed: &old}, Read: true}
+
+		f := RssFeed{
+			RssItems: []*RssItem{unread, bookmarked, recent, stale},
+			Options:  FeedOptions{Retention: Retention{Age: 24 * time.Hour}},
+		}
+		f.applyRetention()
+		if !slices.Equal(f.RssItems, []*RssItem{unread, bookmarked, recent}) {
+			t.Error("Only old read items should be dropped by age")
+		}
+
+		f.RssItems = []*RssItem{stale, recent}
+		f.Options.Retention = Retention{Count: 1}
+		f.applyRetention()
+		if !slices.Equal(f.RssItems, []*RssItem{stale}) {
+			t.Error("Read items beyond count should be dropped")
+		}
+	})
+
+	t.Run("Should not bring back items dropped by retention when fetching again", func(t *testing.T) {
+		guids := []string{"3", "2", "1"}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>T</title>`)
+			for _, guid := range guids {
+				fmt.Fprintf(w, `<item><guid>%s</guid><title>%s</title><pubDate>Mon, 0%s Jan 2006 15:04:05 GMT</pubDate></item>`, guid, guid, guid)
+			}
+			fmt.Fprint(w, `</channel></rss>`)
+		}))
+		defer server.Close()
+
+		f := &RssFeed{Url: server.URL, Options: FeedOptions{Retention: Retention{Count: 1}}}
+		if err := f.GetFeed(); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		for _, item := range f.RssItems {
+			item.MarkRead()
+		}
+		f.applyRetention()
+
+		if err := f.GetFeed(); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if len(f.RssItems) != 1 || f.RssItems[0].Key() != "3" || !f.RssItems[0].Read {
+			t.Errorf("Dropped items should stay dropped, got %d items", len(f.RssItems))
+		}
+
+		fetched := &RssFeed{Url: server.URL}
+		if err := fetched.GetFeed(); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if added := f.MergeFetched(fetched); added != 0 || len(f.RssItems) != 1 {
+			t.Errorf("Merging a fresh copy should not bring back dropped items, added %d", added)
+		}
+
+		guids = guids[:2]
+		if err := f.GetFeed(); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if !slices.Equal(f.Dropped, []string{"2"}) {
+			t.Errorf("Items no longer served should be forgotten, got %v", f.Dropped)
+		}
+	})
+
+	t.Run("Should load with duplicates and keep them as warnings", func(t *testing.T) {
+		l, err := loadUrls(t, "news:\n  - https://example.com/feed.xml\n  - https://example.com/feed.xml\n")
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if len(l.Warnings) != 1 || l.Warnings[0].Line != 3 || !errors.Is(l.Warnings[0], ErrDuplicateFeed) {
+			t.Errorf("Expected ErrDuplicateFeed on line 3, got %v", l.Warnings)
+		}
+	})
+
+	t.Run("Should parse days and weeks", func(t *testing.T) {
+		for in, want := range map[string]time.Duration{
+			"90m": 90 * time.Minute,
+			"7d":  7 * 24 * time.Hour,
+			"2w":  14 * 24 * time.Hour,
+		} {
+			if got, err := ParseDuration(in); err != nil || got != want {
+				t.Errorf("ParseDuration(%q) = %v, %v", in, got, err)
+			}
+		}
+		if _, err := ParseDuration("-1d"); err == nil {
+			t.Error("Negative duration should be rejected")
+		}
+	})
+}
@@ -2,123 +2,240 @@ package tui
 
 import (
 	"fmt"
-	"os"
-	"os/exec"
-	"path/filepath"
-	"runtime"
 	"slices"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/emilosman/rssboat/internal/rss"
-	"github.com/muesli/reflow/wordwrap"
 )
 
 type keyHandler func(*model) tea.Cmd
 
+// Number of recently read items shown in the history view
+const historyLimit = 200
+
 var (
-	feedKeyHandlers = map[string]keyHandler{
-		"A": handleMarkFeedRead,
-		"b": handlePrevUnreadFeed,
-		"B": handleViewBookmarks,
-		//"C":      handleMarkAllFeedsRead,
-		"E":      handleEdit,
-		"h":      handlePrevTab,
-		"left":   handlePrevTab,
-		"l":      handleNextTab,
-		"right":  handleNextTab,
-		"n":      handleNextUnreadFeed,
-		"o":      handleOpenFeed,
-		"p":      handlePrevUnreadFeed,
-		"r":      handleUpdateFeed,
-		"R":      handleUpdateAllFeeds,
-		"q":      handleQuit,
-		"ctrl+a": handleMarkTabAsRead,
-		"ctrl+c": handleInterrupt,
-		"ctrl+r": handleTabUpdate,
-		"enter":  handleEnterFeed,
-		"esc":    handleQuit,
-		"tab":    handleNextTab,
-	}
-
-	itemKeyHandlers = map[string]keyHandler{
-		"a":     handleToggleRead,
-		"A":     handleMarkItemsRead,
-		"b":     handleBack,
-		"B":     handleViewBookmarks,
-		"c":     handleToggleBookmark,
-		"n":     handleNextUnreadItem,
-		"o":     handleOpenItem,
-		"p":     handlePrevUnreadItem,
-		"q":     handleBack,
-		"esc":   handleBack,
-		"r":     handleUpdateFeed,
-		"R":     handleUpdateAllFeeds,
-		"enter": handleViewItem,
-	}
-
-	viewKeyHandlers = map[string]keyHandler{
-		"a":     handleToggleRead,
-		"b":     handleBack,
-		"B":     handleViewBookmarks,
-		"c":     handleToggleBookmark,
-		"l":     handleViewNext,
-		"right": handleViewNext,
-		"h":     handleViewPrev,
-		"left":  handleViewPrev,
-		"o":     handleOpenItem,
-		"enter": handleOpenItem,
-		"q":     handleBack,
-		"esc":   handleBack,
-		"?":     handleViewHelp,
+	feedActions = []action{
+		{name: "prev_tab", help: "previous tab", handler: handlePrevTab, keys: []string{"left", "h"}, short: true},
+		{name: "next_tab", help: "next tab", handler: handleNextTab, keys: []string{"right", "l", "tab"}, short: true},
+		{name: "view", help: "view feed", handler: handleEnterFeed, keys: []string{"enter"}, short: true},
+		{name: "next_unread", help: "next unread feed", handler: handleNextUnreadFeed, keys: []string{"n"}},
+		{name: "prev_unread", help: "previous unread feed", handler: handlePrevUnreadFeed, keys: []string{"p", "b"}},
+		{name: "open", help: "open website", handler: handleOpenFeed, keys: []string{"o"}},
+		{name: "refresh", help: "refresh single feed", handler: handleUpdateFeed, keys: []string{"r"}},
+		{name: "refresh_all", help: "refresh all feeds", handler: handleUpdateAllFeeds, keys: []string{"R"}, short: true},
+		{name: "refresh_tab", help: "refresh tab", handler: handleTabUpdate, keys: []string{"ctrl+r"}},
+		{name: "mark_read", help: "mark feed as read", handler: handleMarkFeedRead, keys: []string{"A"}},
+		{name: "mark_tab_read", help: "mark tab as read", handler: handleMarkTabAsRead, keys: []string{"ctrl+a"}},
+		{name: "mark_all_read", help: "mark all feeds as read", handler: handleMarkAllFeedsRead},
+		{name: "bookmarks", help: "bookmarks list", handler: handleViewBookmarks, keys: []string{"B"}},
+		{name: "history", help: "recently read history", handler: handleViewHistory, keys: []string{"H"}},
+		{name: "edit", help: "edit URLs file", handler: handleEdit, keys: []string{"E"}, short: true},
+		{name: "import", help: "import OPML file", handler: handleImport, keys: []string{"I"}},
+		{name: "add_feed", help: "add feed", handler: handleAddFeed, keys: []string{"a"}},
+		{name: "delete_feed", help: "delete feed", handler: handleDeleteFeed, keys: []string{"D"}},
+		{name: "move_feed", help: "move feed to category", handler: handleMoveFeed, keys: []string{"m"}},
+		{name: "rename_tab", help: "rename tab", handler: handleRenameTab, keys: []string{"c"}},
+		{name: "quit", help: "quit", handler: handleQuit, keys: []string{"q", "esc"}, short: true},
+		{name: "interrupt", handler: handleInterrupt, keys: []string{"ctrl+c"}},
+	}
+
+	itemActions = []action{
+		{name: "toggle_read", help: "toggle read", handler: handleToggleRead, keys: []string{"a"}, short: true},
+		{name: "toggle_bookmark", help: "bookmark item", handler: handleToggleBookmark, keys: []string{"c"}, short: true},
+		{name: "open", help: "open item url", handler: handleOpenItem, keys: []string{"o"}, short: true},
+		{name: "back", help: "back", handler: handleBack, keys: []string{"b", "q", "esc"}, short: true},
+		{name: "refresh", help: "refresh feed", handler: handleUpdateFeed, keys: []string{"r"}, short: true},
+		{name: "mark_read", help: "mark all items read", handler: handleMarkItemsRead, keys: []string{"A"}, short: true},
+		{name: "view", help: "preview item", handler: handleViewItem, keys: []string{"enter"}, short: true},
+		{name: "next_unread", help: "next unread item", handler: handleNextUnreadItem, keys: []string{"n"}},
+		{name: "prev_unread", help: "previous unread item", handler: handlePrevUnreadItem, keys: []string{"p"}},
+		{name: "refresh_all", help: "refresh all feeds", handler: handleUpdateAllFeeds, keys: []string{"R"}},
+		{name: "bookmarks", help: "bookmarks list", handler: handleViewBookmarks, keys: []string{"B"}},
+		{name: "history", help: "recently read history", handler: handleViewHistory, keys: []string{"H"}},
+	}
+
+	articleActions = []action{
+		{name: "prev", help: "previous article", handler: handleViewPrev, keys: []string{"left", "h"}, short: true},
+		{name: "next", help: "next article", handler: handleViewNext, keys: []string{"right", "l"}, short: true},
+		{name: "toggle_read", help: "toggle read", handler: handleToggleRead, keys: []string{"a"}, short: true},
+		{name: "toggle_bookmark", help: "bookmark item", handler: handleToggleBookmark, keys: []string{"c"}, short: true},
+		{name: "open", help: "open website", handler: handleOpenItem, keys: []string{"o", "enter"}, short: true},
+		{name: "back", help: "back", handler: handleBack, keys: []string{"b", "q", "esc"}, short: true},
+		{name: "bookmarks", help: "bookmarks list", handler: handleViewBookmarks, keys: []string{"B"}},
+		{name: "help", help: "toggle help", handler: handleViewHelp, keys: []string{"?"}},
 	}
 )
 
 func handleEdit(m *model) tea.Cmd {
-	configFilePath, err := rss.ConfigFilePath()
-	if err != nil {
-		fmt.Println("Error opening config dir", err)
+	if managedByBackend(m) {
 		return nil
 	}
-	configFile := filepath.Join(configFilePath, "urls.yaml")
 
-	editor := os.Getenv("EDITOR")
-	if editor == "" {
-		switch runtime.GOOS {
-		case "windows":
-			editor = "notepad"
-		default:
-			editor = "nvim"
+	return editUrls(m, 0)
+}
+
+func handleImport(m *model) tea.Cmd {
+	if managedByBackend(m) {
+		return nil
+	}
+
+	return startPrompt(m, MsgImportPrompt, "", func(m *model, path string) tea.Cmd {
+		added, total, err := importOpml(path)
+		if err != nil {
+			m.UpdateStatus(err.Error())
+			return nil
+		}
+
+		feeds, err := reconcileUrls(m)
+		if err != nil {
+			m.UpdateStatus(err.Error())
+			return nil
 		}
+
+		m.UpdateStatus(fmt.Sprintf(MsgImported, added, total))
+		return tea.Batch(updateFeedsCmd(m, feeds), fetchIncludesCmd(m))
+	})
+}
+
+func handleAddFeed(m *model) tea.Cmd {
+	if managedByBackend(m) {
+		return nil
 	}
 
-	m.SaveState()
-	m.prog.ReleaseTerminal()
-	cmd := exec.Command(editor, configFile)
+	return startPrompt(m, MsgAddFeedPrompt, "", func(m *model, url string) tea.Cmd {
+		m.UpdateStatus(fmt.Sprintf("%s %s", MsgUpdatingFeed, url))
+		return previewFeedCmd(url)
+	})
+}
 
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+// Asks for the category of a fetched feed, then adds it to urls.yaml
+func handleFeedPreview(m *model, msg feedPreviewMsg) tea.Cmd {
+	if msg.Err != nil {
+		m.UpdateStatus(fmt.Sprintf("%s: %v", ErrUpdatingFeed, msg.Err))
+		return nil
+	}
 
-	err = cmd.Run()
-	if err != nil {
-		m.UpdateStatus(err.Error())
+	preview := msg.Feed
+	label := fmt.Sprintf(MsgAddFeedCategory, preview.Name(), len(preview.RssItems))
+	return startPrompt(m, label, activeTab(m.tabs, m.activeTab), func(m *model, category string) tea.Cmd {
+		added, err := editUrlsFile(m, func(filename string, data []byte) ([]byte, error) {
+			out, added, err := rss.AddToUrls(filename, data, []*rss.RssFeed{{Url: preview.Url, Category: category}})
+			if err == nil && len(added) == 0 {
+				err = ErrFeedExists
+			}
+			return out, err
+		})
+		if err != nil {
+			m.UpdateStatus(err.Error())
+			return nil
+		}
+
+		// The preview already fetched the feed
+		for _, f := range added {
+			f.Feed, f.RssItems, f.FetchedAt = preview.Feed, preview.RssItems, preview.FetchedAt
+		}
+		m.activeTab = max(slices.Index(m.tabs, category), 0)
+		rebuildFeedList(m)
+		m.UpdateStatus(fmt.Sprintf(MsgFeedAdded, preview.Name(), category))
+		return nil
+	})
+}
+
+func handleDeleteFeed(m *model) tea.Cmd {
+	if managedByBackend(m) {
 		return nil
 	}
 
-	m.prog.RestoreTerminal()
-	filesystem := os.DirFS(configFilePath)
-	l, err := rss.LoadList(filesystem)
-	if err != nil {
-		m.UpdateStatus(err.Error())
+	i, ok := m.lf.SelectedItem().(feedItem)
+	if !ok {
+		return nil
+	}
+	feed := i.rssFeed
+	if includedFeed(m, feed) {
 		return nil
 	}
+	category := feedCategory(feed, activeTab(m.tabs, m.activeTab))
 
-	m.l = l
-	m.activeTab = 0
-	m.tabs = l.Categories()
-	m.UpdateStatus("URLs file edited")
+	label := fmt.Sprintf(MsgDeleteFeedPrompt, feed.Name(), category)
+	return startPrompt(m, label, "", func(m *model, answer string) tea.Cmd {
+		if !confirmed(answer) {
+			return nil
+		}
 
-	return rebuildFeedList(m)
+		_, err := editUrlsFile(m, func(filename string, data []byte) ([]byte, error) {
+			return rss.RemoveFromUrls(filename, data, category, feed.Url)
+		})
+		if err != nil {
+			m.UpdateStatus(err.Error())
+			return nil
+		}
+
+		m.UpdateStatus(fmt.Sprintf(MsgFeedDeleted, feed.Name(), category))
+		return nil
+	})
+}
+
+func handleMoveFeed(m *model) tea.Cmd {
+	if managedByBackend(m) {
+		return nil
+	}
+
+	i, ok := m.lf.SelectedItem().(feedItem)
+	if !ok {
+		return nil
+	}
+	feed := i.rssFeed
+	if includedFeed(m, feed) {
+		return nil
+	}
+	from := feedCategory(feed, activeTab(m.tabs, m.activeTab))
+
+	label := fmt.Sprintf(MsgMoveFeedPrompt, feed.Name())
+	return startPrompt(m, label, from, func(m *model, to string) tea.Cmd {
+		_, err := editUrlsFile(m, func(filename string, data []byte) ([]byte, error) {
+			return rss.MoveInUrls(filename, data, feed.Url, from, to)
+		})
+		if err != nil {
+			m.UpdateStatus(err.Error())
+			return nil
+		}
+
+		m.UpdateStatus(fmt.Sprintf(MsgFeedMoved, feed.Name(), to))
+		return nil
+	})
+}
+
+func handleRenameTab(m *model) tea.Cmd {
+	if managedByBackend(m) {
+		return nil
+	}
+
+	tab := activeTab(m.tabs, m.activeTab)
+	if tab == "" {
+		return nil
+	}
+
+	label := fmt.Sprintf(MsgRenameTabPrompt, tab)
+	return startPrompt(m, label, rss.CategoryName(tab), func(m *model, name string) tea.Cmd {
+		renamed := name
+		if parent := rss.ParentCategory(tab); parent != "" {
+			renamed = parent + rss.CategorySeparator + name
+		}
+
+		// Renamed first, so the tab stays selected
+		m.tabs[m.activeTab] = renamed
+		_, err := editUrlsFile(m, func(filename string, data []byte) ([]byte, error) {
+			return rss.RenameCategory(filename, data, tab, name)
+		})
+		if err != nil {
+			m.tabs[m.activeTab] = tab
+			m.UpdateStatus(err.Error())
+			return nil
+		}
+
+		m.UpdateStatus(fmt.Sprintf(MsgTabRenamed, tab, renamed))
+		return nil
+	})
 }
 
 func handleNextTab(m *model) tea.Cmd {
@@ -185,6 +302,17 @@ func handleViewBookmarks(m *model) tea.Cmd {
 	return nil
 }
 
+func handleViewHistory(m *model) tea.Cmd {
+	m.f = m.l.History(historyLimit)
+	m.title = "History"
+	m.i = nil
+
+	rebuildItemsList(m)
+	m.li.Select(0)
+
+	return nil
+}
+
 func handleNextUnreadItem(m *model) tea.Cmd {
 	i, ok := m.li.SelectedItem().(rssListItem)
 	if ok {
@@ -257,7 +385,7 @@ func handleOpenFeed(m *model) tea.Cmd {
 			m.UpdateStatus(err.Error())
 		}
 
-		err = openInBrowser(url)
+		err = openInBrowser(m.cfg, url)
 		if err != nil {
 			m.UpdateStatus(err.Error())
 		}
@@ -270,12 +398,14 @@ func handleOpenItem(m *model) tea.Cmd {
 	if ok {
 		rssItem := i.item
 		if rssItem.Item != nil {
-			err := openInBrowser(rssItem.Link())
+			err := openInBrowser(m.cfg, rssItem.Link())
 			if err != nil {
 				errorMessage := fmt.Sprintf("Error opening item, %q", err)
 				m.UpdateStatus(errorMessage)
 			}
-			rssItem.MarkRead()
+			if m.cfg.MarkRead.OnOpen {
+				rssItem.MarkRead()
+			}
 			rebuildItemsList(m)
 		}
 	}
@@ -306,6 +436,9 @@ func handleUpdateFeed(m *model) tea.Cmd {
 			feed = i.rssFeed
 		}
 	}
+	if feed == nil || !refreshable(m, feed) {
+		return nil
+	}
 
 	message := fmt.Sprintf("%s %s", MsgUpdatingFeed, feed.Url)
 	m.UpdateStatus(message)
@@ -345,8 +478,10 @@ func handleViewItem(m *model) tea.Cmd {
 		m.i = i.item
 		if m.i.Item != nil {
 			m.v.YOffset = 0
-			m.v.SetContent(wordwrap.String(m.i.Content(), 80))
-			m.i.MarkRead()
+			m.v.SetContent(itemContent(m, m.i))
+			if m.cfg.MarkRead.OnView {
+				m.i.MarkRead()
+			}
 			rebuildItemsList(m)
 		}
 	}
@@ -373,8 +508,10 @@ func handleViewNext(m *model) tea.Cmd {
 		m.i = next
 		m.li.Select(index)
 		m.v.YOffset = 0
-		m.v.SetContent(wordwrap.String(next.Content(), m.v.Width))
-		next.MarkRead()
+		m.v.SetContent(itemContent(m, next))
+		if m.cfg.MarkRead.OnView {
+			next.MarkRead()
+		}
 		rebuildItemsList(m)
 	}
 	return nil
@@ -386,8 +523,10 @@ func handleViewPrev(m *model) tea.Cmd {
 		m.i = prev
 		m.li.Select(index)
 		m.v.YOffset = 0
-		m.v.SetContent(wordwrap.String(prev.Content(), m.v.Width))
-		prev.MarkRead()
+		m.v.SetContent(itemContent(m, prev))
+		if m.cfg.MarkRead.OnView {
+			prev.MarkRead()
+		}
 		rebuildItemsList(m)
 	}
 	return nil
@@ -1,253 +0,0 @@
-package tui
-
-import "github.com/charmbracelet/bubbles/key"
-
-func listShortHelp() []key.Binding {
-	return []key.Binding{
-		key.NewBinding(
-			key.WithKeys("←/h"),
-			key.WithHelp("←/h", "left"),
-		),
-		key.NewBinding(
-			key.WithKeys("→/l"),
-			key.WithHelp("→/l", "right"),
-		),
-		key.NewBinding(
-			key.WithKeys("enter"),
-			key.WithHelp("enter", "view feed"),
-		),
-		key.NewBinding(
-			key.WithKeys("shift+r"),
-			key.WithHelp("shift+r", "refresh all"),
-		),
-		key.NewBinding(
-			key.WithKeys("shift+e"),
-			key.WithHelp("shift+e", "edit URLs"),
-		),
-		key.NewBinding(
-			key.WithKeys("q/esc"),
-			key.WithHelp("q/esc", "quit"),
-		),
-	}
-}
-
-func listFullHelp() [][]key.Binding {
-	return [][]key.Binding{
-		{
-			key.NewBinding(
-				key.WithKeys("←/h"),
-				key.WithHelp("←/h", "previous tab"),
-			),
-			key.NewBinding(
-				key.WithKeys("→/l/tab"),
-				key.WithHelp("→/l/tab", "next tab"),
-			),
-			key.NewBinding(
-				key.WithKeys("n"),
-				key.WithHelp("n", "next unread feed"),
-			),
-			key.NewBinding(
-				key.WithKeys("o"),
-				key.WithHelp("o", "open website"),
-			),
-			key.NewBinding(
-				key.WithKeys("p/b"),
-				key.WithHelp("p/b", "previous unread feed"),
-			),
-			key.NewBinding(
-				key.WithKeys("q/esc"),
-				key.WithHelp("q/esc", "quit"),
-			),
-			key.NewBinding(
-				key.WithKeys("r"),
-				key.WithHelp("r", "refresh single feed"),
-			),
-			key.NewBinding(
-				key.WithKeys("enter"),
-				key.WithHelp("enter", "view feed"),
-			),
-			key.NewBinding(
-				key.WithKeys("shift+a"),
-				key.WithHelp("shift+a", "mark feed as read"),
-			),
-			key.NewBinding(
-				key.WithKeys("shift+b"),
-				key.WithHelp("shift+b", "bookmarks list"),
-			),
-			key.NewBinding(
-				key.WithKeys("shift+c"),
-				key.WithHelp("shift+c", "mark all items as read"),
-			),
-			key.NewBinding(
-				key.WithKeys("shift+e"),
-				key.WithHelp("shift+e", "edit URLs file"),
-			),
-			key.NewBinding(
-				key.WithKeys("shift+r"),
-				key.WithHelp("shift+r", "refresh all feeds"),
-			),
-			key.NewBinding(
-				key.WithKeys("ctrl+a"),
-				key.WithHelp("ctrl+a", "mark tab as read"),
-			),
-			key.NewBinding(
-				key.WithKeys("ctrl+r"),
-				key.WithHelp("ctrl+r", "refresh tab"),
-			),
-			key.NewBinding(
-				key.WithKeys("0-9"),
-				key.WithHelp("0-9", "tab number select"),
-			),
-		},
-	}
-
-}
-
-func itemsShortHelp() []key.Binding {
-	return []key.Binding{
-		key.NewBinding(
-			key.WithKeys("a"),
-			key.WithHelp("a", "toggle read"),
-		),
-		key.NewBinding(
-			key.WithKeys("c"),
-			key.WithHelp("c", "bookmark item"),
-		),
-		key.NewBinding(
-			key.WithKeys("o"),
-			key.WithHelp("o", "open item url"),
-		),
-		key.NewBinding(
-			key.WithKeys("b/q/esc"),
-			key.WithHelp("b/q/esc", "back"),
-		),
-		key.NewBinding(
-			key.WithKeys("r"),
-			key.WithHelp("r", "refresh feed"),
-		),
-		key.NewBinding(
-			key.WithKeys("shift+a"),
-			key.WithHelp("shift+a", "mark all items read"),
-		),
-		key.NewBinding(
-			key.WithKeys("enter"),
-			key.WithHelp("enter", "preview item"),
-		),
-	}
-}
-
-func itemsFullHelp() [][]key.Binding {
-	return [][]key.Binding{
-		{
-			key.NewBinding(
-				key.WithKeys("a"),
-				key.WithHelp("a", "toggle read"),
-			),
-			key.NewBinding(
-				key.WithKeys("b/q/esc"),
-				key.WithHelp("b/q/esc", "back"),
-			),
-			key.NewBinding(
-				key.WithKeys("c"),
-				key.WithHelp("c", "bookmark item"),
-			),
-			key.NewBinding(
-				key.WithKeys("n"),
-				key.WithHelp("n", "next unread item"),
-			),
-			key.NewBinding(
-				key.WithKeys("o"),
-				key.WithHelp("o", "open item url"),
-			),
-			key.NewBinding(
-				key.WithKeys("p"),
-				key.WithHelp("p", "previous unread item"),
-			),
-			key.NewBinding(
-				key.WithKeys("r"),
-				key.WithHelp("r", "refresh feed"),
-			),
-			key.NewBinding(
-				key.WithKeys("shift+a"),
-				key.WithHelp("shift+a", "mark all items read"),
-			),
-			key.NewBinding(
-				key.WithKeys("shift+b"),
-				key.WithHelp("shift+b", "bookmarks list"),
-			),
-			key.NewBinding(
-				key.WithKeys("enter"),
-				key.WithHelp("enter", "preview item"),
-			),
-		},
-	}
-}
-
-func viewShortHelp() []key.Binding {
-	return []key.Binding{
-		key.NewBinding(
-			key.WithKeys("←/h"),
-			key.WithHelp("←/h", "previous article"),
-		),
-		key.NewBinding(
-			key.WithKeys("→/l"),
-			key.WithHelp("→/l", "next article"),
-		),
-		key.NewBinding(
-			key.WithKeys("a"),
-			key.WithHelp("a", "toggle read"),
-		),
-		key.NewBinding(
-			key.WithKeys("c"),
-			key.WithHelp("c", "bookmark item"),
-		),
-		key.NewBinding(
-			key.WithKeys("o/enter"),
-			key.WithHelp("o/enter", "open website"),
-		),
-		key.NewBinding(
-			key.WithKeys("b/q/esc"),
-			key.WithHelp("b/q/esc", "back"),
-		),
-	}
-}
-
-func viewFullHelp() [][]key.Binding {
-	return [][]key.Binding{
-		{
-			key.NewBinding(
-				key.WithKeys("←/h"),
-				key.WithHelp("←/h", "previous article"),
-			),
-			key.NewBinding(
-				key.WithKeys("→/l"),
-				key.WithHelp("→/l", "next article"),
-			),
-			key.NewBinding(
-				key.WithKeys("a"),
-				key.WithHelp("a", "toggle read"),
-			),
-			key.NewBinding(
-				key.WithKeys("c"),
-				key.WithHelp("c", "bookmark item"),
-			),
-			key.NewBinding(
-				key.WithKeys("o/enter"),
-				key.WithHelp("o/enter", "open website"),
-			),
-			key.NewBinding(
-				key.WithKeys("b/q/esc"),
-				key.WithHelp("b/q/esc", "back"),
-			),
-			key.NewBinding(
-				key.WithKeys("shift+b"),
-				key.WithHelp("shift+b", "bookmarks list"),
-			),
-		},
-	}
-}
-
-type viewKeyMap struct{}
-
-func (viewKeyMap) ShortHelp() []key.Binding  { return viewShortHelp() }
-func (viewKeyMap) FullHelp() [][]key.Binding { return viewFullHelp() }
@@ -1,84 +1,491 @@
 package tui
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/emilosman/rssboat/internal/config"
+	"github.com/emilosman/rssboat/internal/daemon"
+	"github.com/emilosman/rssboat/internal/opml"
 	"github.com/emilosman/rssboat/internal/rss"
+	"github.com/muesli/reflow/wordwrap"
 )
 
 type feedUpdatedMsg struct {
 	Feed *rss.RssFeed
 	Err  error
+	// Fetched is the copy to merge into Feed, see fetchFeedsCmd
+	Fetched *rss.RssFeed
+}
+
+type changesPushedMsg struct {
+	Err error
 }
 
 type feedsDoneMsg struct{}
 type statusClearMsg struct{}
+type refreshTickMsg struct{}
+
+type feedPreviewMsg struct {
+	Feed *rss.RssFeed
+	Err  error
+}
+type watchTickMsg struct{}
+
+type daemonEventsMsg struct {
+	Changes *daemon.Changes
+	Err     error
+}
+
+type includesFetchedMsg struct {
+	Err error
+}
+
+// How often urls.yaml and config.yaml are checked for changes
+const watchInterval = 2 * time.Second
+
+// How long a poll waits for the daemon's events
+const eventsWait = 30 * time.Second
 
 func updateAllFeedsCmd(m *model) tea.Cmd {
+	results, err := m.l.FetchAllFeeds()
+	return fetchFeedsCmd(m, results, err, MsgUpdatingAllFeeds)
+}
+
+func updateTabFeedsCmd(m *model) tea.Cmd {
+	feeds, err := m.l.GetCategory(activeTab(m.tabs, m.activeTab))
+	if err != nil {
+		return fetchFeedsCmd(m, nil, err, "")
+	}
+	results, err := rss.FetchFeeds(feeds...)
+	return fetchFeedsCmd(m, results, err, MsgUpdatingAllFeeds)
+}
+
+func updateFeedCmd(m *model, feed *rss.RssFeed) tea.Cmd {
+	results, err := rss.FetchFeeds(feed)
+	return fetchFeedsCmd(m, results, err, fmt.Sprintf("%s %s", MsgUpdatingFeed, feed.Url))
+}
+
+// Fetches feeds that were just added to urls.yaml
+func updateFeedsCmd(m *model, feeds []*rss.RssFeed) tea.Cmd {
+	if _, ok := m.l.Backend.(*daemon.Client); ok || len(feeds) == 0 {
+		// The daemon fetches them when it applies urls.yaml, see eventsCmd
+		return nil
+	}
+
+	results, err := rss.FetchFeeds(feeds...)
+	return fetchFeedsCmd(m, results, err, MsgUpdatingAllFeeds)
+}
+
+// Sends the results of rss.FetchFeeds as they come. The feeds are fetched
+// into copies, which are merged when feedUpdatedMsg is handled so only the
+// update loop changes the list.
+func fetchFeedsCmd(m *model, results <-chan rss.FeedResult, err error, status string) tea.Cmd {
 	return func() tea.Msg {
-		results, err := m.l.UpdateAllFeeds()
 		if err != nil {
 			return feedUpdatedMsg{Feed: nil, Err: err}
 		}
 
 		go func() {
 			for res := range results {
-				m.prog.Send(feedUpdatedMsg{Feed: res.Feed, Err: res.Err})
+				m.prog.Send(feedUpdatedMsg{Feed: res.Feed, Err: res.Err, Fetched: res.Fetched})
 			}
 			m.prog.Send(feedsDoneMsg{})
 		}()
 
-		return MsgUpdatingAllFeeds
+		return status
 	}
 }
 
-func updateTabFeedsCmd(m *model) tea.Cmd {
+// Fetches a feed that isn't in the list yet, to show before adding it
+func previewFeedCmd(url string) tea.Cmd {
 	return func() tea.Msg {
-		feeds, err := m.l.GetCategory(activeTab(m.tabs, m.activeTab))
-		if err != nil {
-			return feedUpdatedMsg{Feed: nil, Err: err}
-		}
+		feed := &rss.RssFeed{Url: url}
+		err := feed.GetFeed()
+		return feedPreviewMsg{Feed: feed, Err: err}
+	}
+}
 
-		results, err := rss.UpdateFeeds(feeds...)
-		if err != nil {
-			return feedUpdatedMsg{Feed: nil, Err: err}
-		}
+// Schedules the next periodic refresh, if refresh.interval is set
+func refreshTickCmd(m *model) tea.Cmd {
+	interval := time.Duration(m.cfg.Refresh.Interval)
+	if interval <= 0 {
+		return nil
+	}
+	return tea.Tick(interval, func(time.Time) tea.Msg {
+		return refreshTickMsg{}
+	})
+}
 
-		go func() {
-			for res := range results {
-				m.prog.Send(feedUpdatedMsg{Feed: res.Feed, Err: res.Err})
+func watchTickCmd() tea.Cmd {
+	return tea.Tick(watchInterval, func(time.Time) tea.Msg {
+		return watchTickMsg{}
+	})
+}
+
+// Returns when urls.yaml and config.yaml were last changed
+func configModTimes() (urls, cfg time.Time) {
+	configFilePath, err := rss.ConfigFilePath()
+	if err != nil {
+		return
+	}
+	if info, err := os.Stat(filepath.Join(configFilePath, "urls.yaml")); err == nil {
+		urls = info.ModTime()
+	}
+	if info, err := os.Stat(filepath.Join(configFilePath, config.FileName)); err == nil {
+		cfg = info.ModTime()
+	}
+	return
+}
+
+// Returns when the list's cache was last written
+func cacheModTime(l *rss.List) time.Time {
+	path, err := l.CachePath()
+	if err != nil {
+		return time.Time{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// Reloads the config files that changed since they were last loaded, and
+// merges items another rssboat, such as rssboat refresh, saved to the cache
+func watchConfigFiles(m *model) tea.Cmd {
+	if cache := cacheModTime(m.l); !cache.Equal(m.cacheMod) {
+		m.cacheMod = cache
+		mergeCache(m)
+	}
+
+	urls, cfg := configModTimes()
+
+	var cmd tea.Cmd
+	if !cfg.Equal(m.configMod) {
+		m.configMod = cfg
+		cmd = reloadConfig(m)
+	}
+	if !urls.Equal(m.urlsMod) {
+		m.urlsMod = urls
+		if fromUrls(m.l) {
+			added, err := reconcileUrls(m)
+			if err != nil {
+				m.UpdateStatus(err.Error())
 			}
-			m.prog.Send(feedsDoneMsg{})
-		}()
+			cmd = tea.Batch(cmd, updateFeedsCmd(m, added), fetchIncludesCmd(m))
+		}
+	}
+	return cmd
+}
 
-		return MsgUpdatingAllFeeds
+// Adds items saved to the cache by another rssboat to the running list
+func mergeCache(m *model) {
+	added, err := m.l.MergeCache()
+	if err != nil {
+		m.UpdateStatus(err.Error())
+		return
+	}
+
+	// Read and bookmark changes can come without new items
+	rebuildFeedList(m)
+	if m.f != nil {
+		rebuildItemsList(m)
+	}
+	if added > 0 {
+		m.UpdateStatus(fmt.Sprintf(MsgCacheMerged, added))
 	}
 }
 
-func updateFeedCmd(m *model, feed *rss.RssFeed) tea.Cmd {
+// Applies changes to urls.yaml to the running list, keeping the state of
+// feeds still listed and the selected tab and feed when they still exist.
+// It returns the feeds that were added.
+func reconcileUrls(m *model) ([]*rss.RssFeed, error) {
+	configFilePath, err := rss.ConfigFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	// Feeds are still applied when an include fails. Remote includes come
+	// from the cache, see fetchIncludesCmd.
+	feeds, warnings, includeErr := rss.ReadUrlsWarnings(os.DirFS(configFilePath), "urls.yaml", true)
+	if includeErr != nil && !errors.Is(includeErr, rss.ErrInclude) {
+		return nil, includeErr
+	}
+	m.includesMissing = errors.Is(includeErr, rss.ErrIncludeNotCached)
+
+	tab := activeTab(m.tabs, m.activeTab)
+	var selected *rss.RssFeed
+	if item, ok := m.lf.SelectedItem().(feedItem); ok {
+		selected = item.rssFeed
+	}
+
+	added, removed := m.l.Reconcile(feeds)
+	m.l.Warnings = warnings
+	m.urlsMod, _ = configModTimes()
+	m.tabs = m.l.Categories()
+	m.activeTab = max(slices.Index(m.tabs, tab), 0)
+	rebuildFeedList(m)
+	for i, item := range m.lf.Items() {
+		if item.(feedItem).rssFeed == selected {
+			m.lf.Select(i)
+			break
+		}
+	}
+
+	status := fmt.Sprintf(MsgUrlsReloaded, changeSummary(len(added), len(removed)))
+	if problems := urlsWarning(m.l.Warnings); problems != "" {
+		status = problems
+	}
+	if includeErr != nil {
+		status = includeErr.Error()
+	}
+	m.UpdateStatus(status)
+
+	return added, nil
+}
+
+// Downloads remote includes that aren't cached yet, such as one just added to
+// urls.yaml, then applies urls.yaml again
+func fetchIncludesCmd(m *model) tea.Cmd {
+	if !m.includesMissing {
+		return nil
+	}
+	m.includesMissing = false
+
 	return func() tea.Msg {
-		results, err := rss.UpdateFeeds(feed)
+		configFilePath, err := rss.ConfigFilePath()
 		if err != nil {
-			return feedUpdatedMsg{Feed: nil, Err: err}
+			return includesFetchedMsg{Err: err}
 		}
+		_, err = rss.ReadUrls(os.DirFS(configFilePath), "urls.yaml")
+		return includesFetchedMsg{Err: err}
+	}
+}
 
-		go func() {
-			for res := range results {
-				m.prog.Send(feedUpdatedMsg{Feed: res.Feed, Err: res.Err})
+// Opens urls.yaml in the editor at line, then applies the changes. When the
+// file no longer loads the list is kept, and the editor can be reopened at
+// the error.
+func editUrls(m *model, line int) tea.Cmd {
+	configFilePath, err := rss.ConfigFilePath()
+	if err != nil {
+		m.UpdateStatus(err.Error())
+		return nil
+	}
+	editor := m.cfg.EditorCommand(filepath.Join(configFilePath, "urls.yaml"), line)
+
+	m.SaveState()
+	m.prog.ReleaseTerminal()
+	cmd := exec.Command(editor[0], editor[1:]...)
+
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	err = cmd.Run()
+	m.prog.RestoreTerminal()
+	if err != nil {
+		m.UpdateStatus(err.Error())
+		return nil
+	}
+
+	added, err := reconcileUrls(m)
+	if err != nil {
+		var configErr *rss.ConfigError
+		if errors.As(err, &configErr) {
+			line = configErr.Line
+		}
+
+		return startPrompt(m, fmt.Sprintf(MsgReopenEditor, err), "", func(m *model, answer string) tea.Cmd {
+			if confirmed(answer) {
+				return editUrls(m, line)
 			}
-			m.prog.Send(feedsDoneMsg{})
-		}()
+			m.UpdateStatus(err.Error())
+			return nil
+		})
+	}
+
+	return tea.Batch(updateFeedsCmd(m, added), fetchIncludesCmd(m))
+}
+
+// Writes an edit to urls.yaml and applies it to the list, returning the feeds
+// that were added
+func editUrlsFile(m *model, edit func(filename string, data []byte) ([]byte, error)) ([]*rss.RssFeed, error) {
+	configFilePath, err := rss.ConfigFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rss.EditUrlsFile(filepath.Join(configFilePath, "urls.yaml"), edit); err != nil {
+		return nil, err
+	}
+	return reconcileUrls(m)
+}
+
+// Returns the category a feed is listed in under a tab, which is the tab
+// itself or one of its nested categories
+func feedCategory(feed *rss.RssFeed, tab string) string {
+	for _, category := range feed.Categories {
+		if category == tab || strings.HasPrefix(category, tab+rss.CategorySeparator) {
+			return category
+		}
+	}
+	return feed.Category
+}
+
+// Reports whether a feed comes from an include, and can't be edited here
+func includedFeed(m *model, feed *rss.RssFeed) bool {
+	if feed.Source == "" {
+		return false
+	}
+	m.UpdateStatus(fmt.Sprintf(MsgIncludedFeed, feed.Source))
+	return true
+}
+
+// Reports whether a feed can be refreshed. Bookmarks and History gather items
+// of other feeds and have no URL to fetch.
+func refreshable(m *model, feed *rss.RssFeed) bool {
+	if m.l.FeedIndex[feed.Url] == feed && feed != m.l.Bookmarks() {
+		return true
+	}
+	m.UpdateStatus(fmt.Sprintf(MsgNotRefreshable, feed.Url))
+	return false
+}
+
+// Reports whether the list's feeds come from urls.yaml, directly or through
+// the daemon
+func fromUrls(l *rss.List) bool {
+	_, ok := l.Backend.(*daemon.Client)
+	return l.Backend == nil || ok
+}
+
+// Reports whether feeds are managed by a backend, and can't be edited here
+func managedByBackend(m *model) bool {
+	if fromUrls(m.l) {
+		return false
+	}
+	m.UpdateStatus(fmt.Sprintf(MsgManagedByBackend, m.l.Backend.Name()))
+	return true
+}
+
+func confirmed(answer string) bool {
+	return strings.HasPrefix(strings.ToLower(answer), "y")
+}
+
+// Describes added and removed feeds, such as "+3 feeds, -1 feed"
+func changeSummary(added, removed int) string {
+	plural := func(n int) string {
+		if n == 1 {
+			return "feed"
+		}
+		return "feeds"
+	}
+
+	var parts []string
+	if added > 0 {
+		parts = append(parts, fmt.Sprintf("+%d %s", added, plural(added)))
+	}
+	if removed > 0 {
+		parts = append(parts, fmt.Sprintf("-%d %s", removed, plural(removed)))
+	}
+	if len(parts) == 0 {
+		return MsgNoFeedChanges
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Loads config.yaml again, keeping the current settings when it has errors
+func reloadConfig(m *model) tea.Cmd {
+	cfg, err := config.LoadUserConfig()
+	if err != nil {
+		m.UpdateStatus(err.Error())
+		return nil
+	}
+
+	keys, err := newKeymaps(cfg.Keys)
+	if err != nil {
+		m.UpdateStatus(err.Error())
+		return nil
+	}
 
-		return fmt.Sprintf("%s %s", MsgUpdatingFeed, feed.Url)
+	// The list delegates hold the keymaps for their help, so they are
+	// updated in place
+	*m.keys.feeds, *m.keys.items, *m.keys.article = *keys.feeds, *keys.items, *keys.article
+
+	wasRefreshing := m.cfg.Refresh.Interval > 0
+	m.cfg = cfg
+	applyTheme(cfg.Theme)
+	rebuildFeedList(m)
+	if m.f != nil {
+		rebuildItemsList(m)
+	}
+	if m.i != nil {
+		m.v.SetContent(itemContent(m, m.i))
+	}
+	m.UpdateStatus(MsgConfigReloaded)
+
+	if !wasRefreshing {
+		return refreshTickCmd(m)
+	}
+	return nil
+}
+
+// Pushes read and bookmark changes to the backend when there are any, one
+// push at a time. Changes made while a push is running go with the next one.
+func pushChangesCmd(m *model) tea.Cmd {
+	if m.l.Backend == nil || m.pushing || !m.l.HasPending() {
+		return nil
+	}
+	m.pushing = true
+	return func() tea.Msg {
+		return changesPushedMsg{Err: m.l.PushChanges()}
+	}
+}
+
+// Waits for the daemon's events when the list comes from it, and sends what
+// they changed
+func eventsCmd(m *model) tea.Cmd {
+	c, ok := m.l.Backend.(*daemon.Client)
+	if !ok {
+		return nil
+	}
+	return func() tea.Msg {
+		changes, err := c.Poll(eventsWait)
+		return daemonEventsMsg{Changes: changes, Err: err}
 	}
 }
 
+// Applies items the daemon fetched and changes other clients made, then
+// waits for more
+func applyDaemonChanges(m *model, changes *daemon.Changes) tea.Cmd {
+	var cmd tea.Cmd
+	if changes.FeedsChanged {
+		// The daemon applied urls.yaml before the watcher saw it
+		cmd = watchConfigFiles(m)
+	}
+
+	if added := changes.Apply(m.l); added > 0 {
+		m.UpdateStatus(fmt.Sprintf(MsgCacheMerged, added))
+	}
+	if len(changes.Events) > 0 {
+		rebuildFeedList(m)
+		if m.f != nil {
+			rebuildItemsList(m)
+		}
+	}
+	return tea.Batch(cmd, eventsCmd(m))
+}
+
 // Builds the feed list and sets the items
 func rebuildFeedList(m *model) tea.Cmd {
 	items := buildFeedList(m.l, m.tabs, m.activeTab)
@@ -107,6 +514,9 @@ func buildFeedList(l *rss.List, t []string, a int) []list.Item {
 		for _, feed := range feeds {
 			title := feed.Title()
 			description := feed.Latest()
+			if feed.Source != "" {
+				description = fmt.Sprintf("%s · %s", feed.Source, description)
+			}
 
 			if feed.HasUnread() {
 				title = unreadStyle.Render(title)
@@ -162,39 +572,68 @@ func buildItemsList(feed *rss.RssFeed) []list.Item {
 func renderedTabs(m *model) string {
 	var renderedTabs string
 	for i, tab := range m.tabs {
-		if i == m.activeTab {
-			renderedTabs += activeTabStyle.Render(tab)
-		} else {
-			feeds, _ := m.l.GetCategory(tab)
-			hasUnread := false
-			for _, f := range feeds {
-				if f.HasUnread() {
-					hasUnread = true
-					break
-				}
-			}
-			if hasUnread {
-				renderedTabs += unreadTabStyle.Render(tab)
-			} else {
-				renderedTabs += inactiveTabStyle.Render(tab)
-			}
+		label, unread := tabLabel(m.l, tab)
+		switch {
+		case i == m.activeTab:
+			renderedTabs += activeTabStyle.Render(label)
+		case unread > 0:
+			renderedTabs += unreadTabStyle.Render(label)
+		default:
+			renderedTabs += inactiveTabStyle.Render(label)
 		}
 	}
 
 	return renderedTabs
 }
 
+// Nested tabs are shown as "/name" after their parent, unread counts
+// include the feeds of subcategories
+func tabLabel(l *rss.List, tab string) (string, int) {
+	label := tab
+	if rss.CategoryDepth(tab) > 0 {
+		label = rss.CategorySeparator + rss.CategoryName(tab)
+	}
+
+	feeds, _ := l.GetCategory(tab)
+	unread := 0
+	for _, f := range feeds {
+		unread += f.UnreadCount()
+	}
+
+	if unread > 0 {
+		label = fmt.Sprintf("%s (%d)", label, unread)
+	}
+
+	return label, unread
+}
+
 func renderedTitle(m *model) string {
 	return titleStyle.Render(m.title)
 }
 
 func renderedStatus(m *model) string {
+	if m.onSubmit != nil {
+		return statusStyle.Render(m.prompt.View())
+	}
 	return statusStyle.Render(m.status)
 }
 
-func openInBrowser(url string) error {
+// Wraps an item for the viewport at display.wrap_width, or the viewport width
+func itemContent(m *model, item *rss.RssItem) string {
+	width := m.cfg.Display.WrapWidth
+	if width == 0 {
+		width = m.v.Width
+	}
+	return wordwrap.String(item.FormatContent(m.cfg.Display.DateFormat), width)
+}
+
+func openInBrowser(cfg *config.Config, url string) error {
 	var cmd *exec.Cmd
 
+	if browser := cfg.BrowserCommand(url); browser != nil {
+		return exec.Command(browser[0], browser[1:]...).Start()
+	}
+
 	switch runtime.GOOS {
 	case "darwin":
 		cmd = exec.Command("open", url)
@@ -209,6 +648,88 @@ func openInBrowser(url string) error {
 	return cmd.Start()
 }
 
+// notify shows a desktop notification, for feeds with notify set
+func notify(title, body string) error {
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %q with title %q", body, title)
+		cmd = exec.Command("osascript", "-e", script)
+	case "linux":
+		cmd = exec.Command("notify-send", title, body)
+	default:
+		return fmt.Errorf("unsupported platform: %s", runtime.GOOS)
+	}
+
+	return cmd.Start()
+}
+
+// The first problem of urls.yaml found while loading, or nothing when it's fine
+func urlsWarning(warnings []*rss.ConfigError) string {
+	if len(warnings) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(MsgUrlsProblems, warnings[0], len(warnings))
+}
+
+// Adds the feeds of an OPML file to urls.yaml
+func importOpml(path string) (added, total int, err error) {
+	if home, err := os.UserHomeDir(); err == nil {
+		if rest, ok := strings.CutPrefix(path, "~/"); ok {
+			path = filepath.Join(home, rest)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	feeds, err := opml.Parse(f, false)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	configFilePath, err := rss.ConfigFilePath()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	imported, err := rss.AddToUrlsFile(filepath.Join(configFilePath, "urls.yaml"), feeds)
+	return len(imported), len(feeds), err
+}
+
+// Shows a text prompt in place of the status line, filled in with value.
+// submit runs on enter.
+func startPrompt(m *model, label, value string, submit func(*model, string) tea.Cmd) tea.Cmd {
+	m.prompt = textinput.New()
+	m.prompt.Prompt = label + " "
+	m.prompt.SetValue(value)
+	m.onSubmit = submit
+	return m.prompt.Focus()
+}
+
+func updatePrompt(m *model, msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "enter":
+		submit, value := m.onSubmit, strings.TrimSpace(m.prompt.Value())
+		m.onSubmit = nil
+		if value == "" {
+			return nil
+		}
+		return submit(m, value)
+	case "esc", "ctrl+c":
+		m.onSubmit = nil
+		return nil
+	}
+
+	var cmd tea.Cmd
+	m.prompt, cmd = m.prompt.Update(msg)
+	return cmd
+}
+
 func (m *model) UpdateTitle(title string) {
 	m.title = title
 }
@@ -220,24 +741,20 @@ func (m *model) UpdateStatus(msg string) {
 		m.clearTimer.Stop()
 	}
 
-	m.clearTimer = time.AfterFunc(8*time.Second, func() {
+	timeout := time.Duration(m.cfg.Display.StatusTimeout)
+	if timeout <= 0 {
+		return
+	}
+
+	m.clearTimer = time.AfterFunc(timeout, func() {
 		m.prog.Send(statusClearMsg{})
 	})
 }
 
 func (m *model) SaveState() error {
-	cacheFilePath, err := rss.CacheFilePath()
-	if err != nil {
-		return err
-	}
-
-	f, err := os.Create(cacheFilePath)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	return m.l.Save(f)
+	err := m.l.SaveCache()
+	m.cacheMod = cacheModTime(m.l)
+	return err
 }
 
 func BuildApp() {
@@ -245,7 +762,11 @@ func BuildApp() {
 	p := tea.NewProgram(m)
 	m.prog = p
 
-	if _, err := p.Run(); err != nil {
+	_, err := p.Run()
+	if m.instance != nil {
+		m.instance.Unlock()
+	}
+	if err != nil {
 		fmt.Println("Error running program:", err)
 		os.Exit(1)
 	}
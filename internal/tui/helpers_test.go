@@ -1,8 +1,19 @@
 package tui
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
 	"testing"
+	"time"
 
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/emilosman/rssboat/internal/config"
+	"github.com/emilosman/rssboat/internal/daemon"
+	"github.com/emilosman/rssboat/internal/miniflux"
 	"github.com/emilosman/rssboat/internal/rss"
 	"github.com/mmcdole/gofeed"
 )
@@ -64,4 +75,261 @@ func TestHelpers(t *testing.T) {
 			t.Errorf("No list items returned")
 		}
 	})
+	t.Run("Should label tabs with unread counts", func(t *testing.T) {
+		l := newList()
+
+		label, unread := tabLabel(&l, "Fun")
+		if label != "Fun (1)" || unread != 1 {
+			t.Errorf("Wrong tab label, got %q", label)
+		}
+
+		label, _ = tabLabel(&l, "Serious")
+		if label != "Serious" {
+			t.Errorf("Tab without unread items should not show count, got %q", label)
+		}
+	})
+
+	t.Run("Should label nested tabs and roll up unread counts", func(t *testing.T) {
+		l := newList()
+		l.CategoryIndex["Fun/Games"] = l.CategoryIndex["Fun"]
+		delete(l.CategoryIndex, "Fun")
+
+		label, _ := tabLabel(&l, "Fun/Games")
+		if label != "/Games (1)" {
+			t.Errorf("Wrong nested tab label, got %q", label)
+		}
+
+		label, _ = tabLabel(&l, "Fun")
+		if label != "Fun (1)" {
+			t.Errorf("Parent tab should roll up unread count, got %q", label)
+		}
+	})
+
+	t.Run("Should not refresh the history or bookmarks", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Display.StatusTimeout = 0
+		l := rss.NewListWithDefaults()
+		l.AddFeeds(&rss.RssFeed{Url: "https://example.com/feed.xml", Category: "news"})
+		m := &model{cfg: cfg, keys: defaultKeymaps(), l: l, li: list.New(nil, list.NewDefaultDelegate(), 0, 0)}
+
+		handleViewHistory(m)
+		if cmd := handleUpdateFeed(m); cmd != nil {
+			t.Error("History should not be refreshed")
+		}
+		if !refreshable(m, l.FeedIndex["https://example.com/feed.xml"]) || refreshable(m, l.Bookmarks()) {
+			t.Error("Only feeds of the list should be refreshable")
+		}
+	})
+
+	t.Run("Should merge fetched copies on the update loop", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Display.StatusTimeout = 0
+		l := rss.NewListWithDefaults()
+		feed := &rss.RssFeed{Url: "https://example.com/feed.xml", Category: "news"}
+		l.AddFeeds(feed)
+		m := &model{cfg: cfg, keys: defaultKeymaps(), l: l, lf: list.New(nil, list.NewDefaultDelegate(), 0, 0), tabs: l.Categories()}
+
+		now := time.Now()
+		fetched := &rss.RssFeed{Url: feed.Url, FetchedAt: &now, Feed: &gofeed.Feed{Title: "Example"}, RssItems: []*rss.RssItem{
+			{Item: &gofeed.Item{GUID: "a"}},
+		}}
+		m.Update(feedUpdatedMsg{Feed: feed, Fetched: fetched})
+
+		if len(feed.RssItems) != 1 || feed.Name() != "Example" {
+			t.Errorf("Fetched copy should be merged into the feed, got %d items", len(feed.RssItems))
+		}
+	})
+
+	t.Run("Should summarise added and removed feeds", func(t *testing.T) {
+		cases := map[[2]int]string{
+			{3, 1}: "+3 feeds, -1 feed",
+			{1, 0}: "+1 feed",
+			{0, 2}: "-2 feeds",
+			{0, 0}: MsgNoFeedChanges,
+		}
+		for counts, want := range cases {
+			if got := changeSummary(counts[0], counts[1]); got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		}
+	})
+
+	t.Run("Should reconcile urls.yaml when it changes", func(t *testing.T) {
+		t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+		t.Setenv("XDG_CACHE_HOME", t.TempDir())
+		configFilePath, err := rss.ConfigFilePath()
+		if err != nil {
+			t.Fatal(err)
+		}
+		urlsPath := filepath.Join(configFilePath, "urls.yaml")
+		os.WriteFile(urlsPath, []byte("news:\n  - https://example.com/a.xml\ntech:\n  - https://example.com/b.xml\n"), 0644)
+
+		cfg := config.Default()
+		cfg.Display.StatusTimeout = 0
+		l, _ := rss.LoadList(os.DirFS(configFilePath))
+		m := &model{cfg: cfg, keys: defaultKeymaps(), l: l, lf: list.New(nil, list.NewDefaultDelegate(), 0, 0), tabs: l.Categories(), activeTab: 1}
+		m.urlsMod, m.configMod = configModTimes()
+		kept := l.FeedIndex["https://example.com/b.xml"]
+
+		os.WriteFile(urlsPath, []byte("art:\n  - https://example.com/c.xml\ntech:\n  - https://example.com/b.xml\n"), 0644)
+		future := time.Now().Add(time.Minute)
+		os.Chtimes(urlsPath, future, future)
+		watchConfigFiles(m)
+
+		if m.status != fmt.Sprintf(MsgUrlsReloaded, "+1 feed, -1 feed") {
+			t.Errorf("Wrong status, got %q", m.status)
+		}
+		if activeTab(m.tabs, m.activeTab) != "tech" {
+			t.Errorf("Tab should be kept, got %q", activeTab(m.tabs, m.activeTab))
+		}
+		if m.l.FeedIndex["https://example.com/b.xml"] != kept {
+			t.Error("Unchanged feed should keep its state")
+		}
+
+		m.status = ""
+		watchConfigFiles(m)
+		if m.status != "" {
+			t.Errorf("Unchanged files should not reload, got %q", m.status)
+		}
+	})
+
+	t.Run("Should download new remote includes outside of reconciling", func(t *testing.T) {
+		t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+		t.Setenv("XDG_CACHE_HOME", t.TempDir())
+		configFilePath, err := rss.ConfigFilePath()
+		if err != nil {
+			t.Fatal(err)
+		}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("team:\n  - https://example.com/team.xml\n"))
+		}))
+		defer server.Close()
+		urlsPath := filepath.Join(configFilePath, "urls.yaml")
+		os.WriteFile(urlsPath, []byte("news:\n  - https://example.com/a.xml\n"), 0644)
+
+		cfg := config.Default()
+		cfg.Display.StatusTimeout = 0
+		l, _ := rss.LoadList(os.DirFS(configFilePath))
+		m := &model{cfg: cfg, keys: defaultKeymaps(), l: l, lf: list.New(nil, list.NewDefaultDelegate(), 0, 0), tabs: l.Categories()}
+
+		os.WriteFile(urlsPath, []byte("include: "+server.URL+"\nnews:\n  - https://example.com/a.xml\n"), 0644)
+		if _, err := reconcileUrls(m); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if _, ok := m.l.FeedIndex["https://example.com/team.xml"]; ok {
+			t.Fatal("Reconciling should not download the include")
+		}
+
+		cmd := fetchIncludesCmd(m)
+		if cmd == nil {
+			t.Fatal("Missing include should be downloaded")
+		}
+		if msg := cmd().(includesFetchedMsg); msg.Err != nil {
+			t.Fatalf("Unexpected error: %q", msg.Err)
+		}
+		if _, err := reconcileUrls(m); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if _, ok := m.l.FeedIndex["https://example.com/team.xml"]; !ok {
+			t.Error("Downloaded include should be applied")
+		}
+		if fetchIncludesCmd(m) != nil {
+			t.Error("Cached include should not be downloaded again")
+		}
+	})
+
+	t.Run("Should merge items saved to the cache by rssboat refresh", func(t *testing.T) {
+		t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+		t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+		cfg := config.Default()
+		cfg.Display.StatusTimeout = 0
+		l := rss.NewListWithDefaults()
+		l.AddFeeds(&rss.RssFeed{Url: "https://example.com/a.xml", Category: "news"})
+		m := &model{cfg: cfg, keys: defaultKeymaps(), l: l, lf: list.New(nil, list.NewDefaultDelegate(), 0, 0), tabs: l.Categories()}
+		m.urlsMod, m.configMod = configModTimes()
+
+		refreshed := rss.NewListWithDefaults()
+		refreshed.AddFeeds(&rss.RssFeed{
+			Url:      "https://example.com/a.xml",
+			Category: "news",
+			RssItems: []*rss.RssItem{{Item: &gofeed.Item{GUID: "1"}}, {Item: &gofeed.Item{GUID: "2"}}},
+		})
+		if err := refreshed.SaveCache(); err != nil {
+			t.Fatal(err)
+		}
+		watchConfigFiles(m)
+
+		if m.status != fmt.Sprintf(MsgCacheMerged, 2) {
+			t.Errorf("Wrong status, got %q", m.status)
+		}
+		if len(l.FeedIndex["https://example.com/a.xml"].RssItems) != 2 {
+			t.Error("Items should be merged into the list")
+		}
+
+		m.status = ""
+		m.SaveState()
+		watchConfigFiles(m)
+		if m.status != "" {
+			t.Errorf("Own save should not be merged, got %q", m.status)
+		}
+	})
+
+	t.Run("Should apply feed management to urls.yaml and the list", func(t *testing.T) {
+		t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+		configFilePath, err := rss.ConfigFilePath()
+		if err != nil {
+			t.Fatal(err)
+		}
+		urlsPath := filepath.Join(configFilePath, "urls.yaml")
+		os.WriteFile(urlsPath, []byte("# mine\nnews:\n  - https://example.com/a.xml\ntech:\n  go:\n    - https://example.com/b.xml\n"), 0644)
+
+		cfg := config.Default()
+		cfg.Display.StatusTimeout = 0
+		l, _ := rss.LoadList(os.DirFS(configFilePath))
+		m := &model{cfg: cfg, keys: defaultKeymaps(), l: l, lf: list.New(nil, list.NewDefaultDelegate(), 0, 0), tabs: l.Categories()}
+		b := l.FeedIndex["https://example.com/b.xml"]
+
+		if category := feedCategory(b, "tech"); category != "tech/go" {
+			t.Errorf("Feed under a parent tab should be in its nested category, got %q", category)
+		}
+
+		_, err = editUrlsFile(m, func(filename string, data []byte) ([]byte, error) {
+			return rss.MoveInUrls(filename, data, b.Url, "tech/go", "news")
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if m.l.FeedIndex[b.Url] != b || !slices.Equal(b.Categories, []string{"news"}) {
+			t.Errorf("Moved feed should keep its state, got categories %v", b.Categories)
+		}
+
+		_, err = editUrlsFile(m, func(filename string, data []byte) ([]byte, error) {
+			return rss.RemoveFromUrls(filename, data, "news", "https://example.com/a.xml")
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if _, ok := m.l.FeedIndex["https://example.com/a.xml"]; ok {
+			t.Error("Deleted feed should be gone from the list")
+		}
+
+		data, _ := os.ReadFile(urlsPath)
+		want := "# mine\nnews:\n  - https://example.com/b.xml\ntech:\n  go:\n"
+		if string(data) != want {
+			t.Errorf("got:\n%s\nwant:\n%s", data, want)
+		}
+		if !slices.Equal(m.tabs, []string{"news"}) {
+			t.Errorf("Tabs should follow urls.yaml, got %v", m.tabs)
+		}
+
+		m.l.Backend = &daemon.Client{}
+		if managedByBackend(m) {
+			t.Error("urls.yaml should be editable when attached to the daemon")
+		}
+		m.l.Backend = miniflux.New("https://miniflux.example.com", "")
+		if !managedByBackend(m) {
+			t.Error("Feeds from a backend should not be editable")
+		}
+	})
 }
@@ -0,0 +1,203 @@
+package tui
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/emilosman/rssboat/internal/config"
+)
+
+// action is a named command that keys can be bound to in config.yaml
+type action struct {
+	name    string
+	help    string
+	handler keyHandler
+	// keys bound when config.yaml doesn't set the action
+	keys []string
+	// short actions are also listed in the short help
+	short bool
+}
+
+// keymap binds the actions of one view to keys
+type keymap struct {
+	view     string
+	actions  []action
+	keys     map[string][]string
+	handlers map[string]keyHandler
+}
+
+type keymaps struct {
+	feeds, items, article *keymap
+}
+
+// newKeymap binds actions to their default keys, replaced by any bindings
+// from config. Unknown actions, keys bound to two actions and reserved keys
+// are errors, reserved maps a key to what it is kept for.
+func newKeymap(view string, actions []action, bindings map[string]config.KeyList, reserved map[string]string) (*keymap, error) {
+	km := &keymap{
+		view:     view,
+		actions:  actions,
+		keys:     make(map[string][]string),
+		handlers: make(map[string]keyHandler),
+	}
+
+	for name := range bindings {
+		if !slices.ContainsFunc(actions, func(a action) bool { return a.name == name }) {
+			return nil, fmt.Errorf("%w: keys.%s.%s", ErrUnknownAction, view, name)
+		}
+	}
+
+	bound := maps.Clone(reserved)
+	if bound == nil {
+		bound = make(map[string]string)
+	}
+
+	for _, a := range actions {
+		keys := a.keys
+		if custom, ok := bindings[a.name]; ok {
+			keys = custom
+		}
+
+		for _, k := range keys {
+			if other, ok := bound[k]; ok {
+				return nil, fmt.Errorf("%w: %q is bound to both %s and %s in keys.%s", ErrKeyConflict, k, other, a.name, view)
+			}
+			bound[k] = a.name
+			km.handlers[k] = a.handler
+		}
+		km.keys[a.name] = keys
+	}
+
+	return km, nil
+}
+
+// newKeymaps builds the keymaps of every view from the keys section of config.yaml
+func newKeymaps(bindings map[string]map[string]config.KeyList) (keymaps, error) {
+	var (
+		km  keymaps
+		err error
+	)
+
+	for view := range bindings {
+		if !slices.Contains([]string{"feeds", "items", "article"}, view) {
+			return km, fmt.Errorf("%w: keys.%s", ErrUnknownView, view)
+		}
+	}
+
+	feedsReserved := listKeys(feedActions)
+	for _, k := range tabNumberKeys() {
+		feedsReserved[k] = "tab numbers"
+	}
+
+	if km.feeds, err = newKeymap("feeds", feedActions, bindings["feeds"], feedsReserved); err != nil {
+		return km, err
+	}
+	if km.items, err = newKeymap("items", itemActions, bindings["items"], listKeys(itemActions)); err != nil {
+		return km, err
+	}
+	if km.article, err = newKeymap("article", articleActions, bindings["article"], nil); err != nil {
+		return km, err
+	}
+
+	return km, nil
+}
+
+// CheckKeys reports the first problem with the keys section of config.yaml
+func CheckKeys(bindings map[string]map[string]config.KeyList) error {
+	_, err := newKeymaps(bindings)
+	return err
+}
+
+func defaultKeymaps() keymaps {
+	km, _ := newKeymaps(nil)
+	return km
+}
+
+// listKeys returns the keys the feed and item lists move and filter with,
+// leaving out those the view's default actions take over, such as h and l
+// switching tabs
+func listKeys(actions []action) map[string]string {
+	lk := list.DefaultKeyMap()
+	keys := make(map[string]string)
+	for _, b := range []key.Binding{lk.CursorUp, lk.CursorDown, lk.PrevPage, lk.NextPage, lk.GoToStart, lk.GoToEnd, lk.Filter, lk.ShowFullHelp} {
+		for _, k := range b.Keys() {
+			keys[k] = "list navigation"
+		}
+	}
+
+	for _, a := range actions {
+		for _, k := range a.keys {
+			delete(keys, k)
+		}
+	}
+	return keys
+}
+
+func tabNumberKeys() []string {
+	keys := make([]string, 10)
+	for i := range keys {
+		keys[i] = fmt.Sprint(i)
+	}
+	return keys
+}
+
+func (km *keymap) handler(k string) (keyHandler, bool) {
+	h, ok := km.handlers[k]
+	return h, ok
+}
+
+func (km *keymap) binding(a action) (key.Binding, bool) {
+	keys := km.keys[a.name]
+	if len(keys) == 0 || a.help == "" {
+		return key.Binding{}, false
+	}
+
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = keyName(k)
+	}
+	label := strings.Join(names, "/")
+
+	return key.NewBinding(key.WithKeys(keys...), key.WithHelp(label, a.help)), true
+}
+
+func (km *keymap) ShortHelp() []key.Binding {
+	var bindings []key.Binding
+	for _, a := range km.actions {
+		if b, ok := km.binding(a); ok && a.short {
+			bindings = append(bindings, b)
+		}
+	}
+	return bindings
+}
+
+func (km *keymap) FullHelp() [][]key.Binding {
+	var bindings []key.Binding
+	for _, a := range km.actions {
+		if b, ok := km.binding(a); ok {
+			bindings = append(bindings, b)
+		}
+	}
+	if km.view == "feeds" {
+		bindings = append(bindings, key.NewBinding(key.WithKeys(tabNumberKeys()...), key.WithHelp("0-9", "tab number select")))
+	}
+	return [][]key.Binding{bindings}
+}
+
+// keyName shows a key the way help has always written it, "R" as "shift+r"
+func keyName(k string) string {
+	switch k {
+	case "left":
+		return "←"
+	case "right":
+		return "→"
+	}
+	if len(k) == 1 && k >= "A" && k <= "Z" {
+		return "shift+" + strings.ToLower(k)
+	}
+	return k
+}
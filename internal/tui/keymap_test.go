@@ -0,0 +1,94 @@
+package tui
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/emilosman/rssboat/internal/config"
+)
+
+func helpLabels(bindings []key.Binding) map[string]string {
+	labels := make(map[string]string)
+	for _, b := range bindings {
+		labels[b.Help().Desc] = b.Help().Key
+	}
+	return labels
+}
+
+func TestKeymap(t *testing.T) {
+	t.Run("Should build default keymaps without conflicts", func(t *testing.T) {
+		km, err := newKeymaps(nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if _, ok := km.feeds.handler("R"); !ok {
+			t.Error("Default key not bound")
+		}
+	})
+
+	t.Run("Should generate help from the active keymap", func(t *testing.T) {
+		km, _ := newKeymaps(map[string]map[string]config.KeyList{
+			"feeds": {"refresh_all": {"R", "f5"}, "mark_all_read": {"C"}},
+		})
+
+		labels := helpLabels(km.feeds.FullHelp()[0])
+		if labels["refresh all feeds"] != "shift+r/f5" {
+			t.Errorf("Wrong help for remapped key, got %q", labels["refresh all feeds"])
+		}
+		if labels["mark all feeds as read"] != "shift+c" {
+			t.Error("Bound action should be in help")
+		}
+
+		if _, ok := km.feeds.handler("f5"); !ok {
+			t.Error("Remapped key not bound")
+		}
+	})
+
+	t.Run("Should leave unbound actions out of help", func(t *testing.T) {
+		km := defaultKeymaps()
+
+		labels := helpLabels(km.feeds.FullHelp()[0])
+		if _, ok := labels["mark all feeds as read"]; ok {
+			t.Error("Unbound action should not be in help")
+		}
+		if _, ok := km.feeds.handler("C"); ok {
+			t.Error("Unbound action should have no key")
+		}
+	})
+
+	t.Run("Should detect conflicting keys", func(t *testing.T) {
+		_, err := newKeymaps(map[string]map[string]config.KeyList{
+			"items": {"toggle_read": {"c"}},
+		})
+		if !errors.Is(err, ErrKeyConflict) {
+			t.Errorf("Expected ErrKeyConflict, got %v", err)
+		}
+
+		_, err = newKeymaps(map[string]map[string]config.KeyList{
+			"feeds": {"refresh": {"1"}},
+		})
+		if !errors.Is(err, ErrKeyConflict) {
+			t.Errorf("Tab number keys should be reserved, got %v", err)
+		}
+
+		_, err = newKeymaps(map[string]map[string]config.KeyList{
+			"feeds": {"delete_feed": {"d"}},
+		})
+		if !errors.Is(err, ErrKeyConflict) {
+			t.Errorf("List navigation keys should be reserved, got %v", err)
+		}
+	})
+
+	t.Run("Should reject unknown actions and views", func(t *testing.T) {
+		_, err := newKeymaps(map[string]map[string]config.KeyList{"feeds": {"explode": {"x"}}})
+		if !errors.Is(err, ErrUnknownAction) {
+			t.Errorf("Expected ErrUnknownAction, got %v", err)
+		}
+
+		_, err = newKeymaps(map[string]map[string]config.KeyList{"tabs": {}})
+		if !errors.Is(err, ErrUnknownView) {
+			t.Errorf("Expected ErrUnknownView, got %v", err)
+		}
+	})
+}
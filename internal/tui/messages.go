@@ -1,5 +1,7 @@
 package tui
 
+import "errors"
+
 var (
 	MsgUpdatingAllFeeds = "Updating all feeds..."
 	MsgAllFeedsUpdated  = "All feeds updated"
@@ -13,6 +15,37 @@ var (
 	MsgUpdatingFeed     = "Updating feed"
 	MsgFeedUpdated      = "Feed updated"
 	MsgNoFeedsInList    = "No feeds in list. Press shift+e to edit URLs file"
+	MsgManagedByBackend = "Feeds are managed by %s"
+	MsgNewItems         = "new items"
+	MsgImportPrompt     = "OPML file to import:"
+	MsgImported         = "Imported %d of %d feeds, the rest were already in urls.yaml"
+	MsgAddFeedPrompt    = "Feed URL:"
+	MsgAddFeedCategory  = "%s (%d items), add to category:"
+	MsgFeedAdded        = "Added %s to %s"
+	MsgDeleteFeedPrompt = "Delete %s from %s? [y/N]"
+	MsgFeedDeleted      = "Deleted %s from %s"
+	MsgMoveFeedPrompt   = "Move %s to category:"
+	MsgFeedMoved        = "Moved %s to %s"
+	MsgRenameTabPrompt  = "Rename %s to:"
+	MsgTabRenamed       = "Renamed %s to %s"
+	MsgNotRefreshable   = "%s gathers items of other feeds, refresh them instead"
+	MsgIncludedFeed     = "Feed comes from %s, list it in urls.yaml with hidden: true to hide it"
+	MsgUrlsReloaded     = "urls.yaml changed: %s"
+	MsgNoFeedChanges    = "no feeds added or removed"
+	MsgConfigReloaded   = "config.yaml reloaded"
+	MsgCacheMerged      = "%d new items fetched in the background"
+	MsgAnotherInstance  = "Another rssboat has this list open, changes are merged on save"
+	MsgReopenEditor     = "%v. Reopen editor? [y/N]"
+	MsgUrlsProblems     = "%s (%d problems, run rssboat check)"
 	ErrUpdatingFeed     = "Error updating feed"
 	ErrUpdatingFeeds    = "Error updating feeds"
+	ErrPushingChanges   = "Error sending changes"
+	ErrDaemonEvents     = "Lost the rssboat daemon"
+)
+
+var (
+	ErrUnknownView   = errors.New("Unknown view in config, use feeds, items or article")
+	ErrUnknownAction = errors.New("Unknown action in config")
+	ErrKeyConflict   = errors.New("Key conflict in config")
+	ErrFeedExists    = errors.New("Feed is already in urls.yaml")
 )
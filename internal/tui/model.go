@@ -1,6 +1,7 @@
 package tui
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
@@ -8,10 +9,14 @@ import (
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/emilosman/rssboat/internal/config"
+	"github.com/emilosman/rssboat/internal/daemon"
+	"github.com/emilosman/rssboat/internal/miniflux"
+	"github.com/emilosman/rssboat/internal/nextcloud"
 	"github.com/emilosman/rssboat/internal/rss"
-	"github.com/muesli/reflow/wordwrap"
 )
 
 type feedItem struct {
@@ -34,6 +39,8 @@ func (r rssListItem) FilterValue() string { return r.item.FilterContent() }
 
 type model struct {
 	prog       *tea.Program
+	cfg        *config.Config
+	keys       keymaps
 	ready      bool
 	title      string
 	status     string
@@ -48,26 +55,103 @@ type model struct {
 	vh         help.Model
 	tabs       []string
 	activeTab  int
+	prompt     textinput.Model
+	// onSubmit is set while the prompt is shown
+	onSubmit func(*model, string) tea.Cmd
+	// when urls.yaml and config.yaml were last loaded
+	urlsMod, configMod time.Time
+	// when the cache was last saved or merged
+	cacheMod time.Time
+	// held while the app is open, nil when another rssboat has the list open
+	instance *rss.FileLock
+	// set while changes are pushed to the backend
+	pushing bool
+	// set when urls.yaml includes a remote file that isn't cached yet
+	includesMissing bool
 }
 
-func initialModel() *model {
+// backend returns the backend set in the environment, nil for urls.yaml
+func backend() rss.Backend {
+	if u := os.Getenv("RSSBOAT_MINIFLUX_URL"); u != "" {
+		return miniflux.New(u, os.Getenv("RSSBOAT_MINIFLUX_TOKEN"))
+	}
+	if u := os.Getenv("RSSBOAT_NEXTCLOUD_URL"); u != "" {
+		return nextcloud.New(u, os.Getenv("RSSBOAT_NEXTCLOUD_USER"), os.Getenv("RSSBOAT_NEXTCLOUD_PASSWORD"))
+	}
+	return nil
+}
+
+// LoadList loads the list from the backend set in the environment, from a
+// running rssboat daemon, or from urls.yaml, with read state from the cache
+func LoadList() (*rss.List, error) {
+	if b := backend(); b != nil {
+		return rss.LoadRemoteList(b)
+	}
+	if c := attachDaemon(); c != nil {
+		l, err := rss.LoadRemoteList(c)
+		if err == nil {
+			err = c.Pull(l)
+		}
+		return l, err
+	}
+
 	configFilePath, err := rss.ConfigFilePath()
 	if err != nil {
-		fmt.Println("Error opening config dir", err)
+		return rss.NewListWithDefaults(), err
 	}
 	filesystem := os.DirFS(configFilePath)
-	l, err := rss.LoadList(filesystem)
+	return rss.LoadList(filesystem)
+}
+
+// attachDaemon connects to rssboat daemon when it is running
+func attachDaemon() *daemon.Client {
+	path, err := daemon.SocketPath()
+	if err != nil {
+		return nil
+	}
+	c, err := daemon.Dial(path)
+	if err != nil {
+		return nil
+	}
+	return c
+}
+
+// LoadCachedList loads the list like LoadList without using the network
+func LoadCachedList() (*rss.List, error) {
+	if b := backend(); b != nil {
+		return rss.LoadCachedRemoteList(b)
+	}
+
+	configFilePath, err := rss.ConfigFilePath()
+	if err != nil {
+		return rss.NewListWithDefaults(), err
+	}
+	return rss.LoadCachedList(os.DirFS(configFilePath))
+}
+
+func initialModel() *model {
+	cfg, cfgErr := config.LoadUserConfig()
+	applyTheme(cfg.Theme)
+
+	l, err := LoadList()
 	t := l.Categories()
 
+	keys, keysErr := newKeymaps(cfg.Keys)
+	if keysErr != nil {
+		keys = defaultKeymaps()
+	}
+
 	df := list.NewDefaultDelegate()
-	df.ShortHelpFunc = listShortHelp
-	df.FullHelpFunc = listFullHelp
+	df.ShortHelpFunc = keys.feeds.ShortHelp
+	df.FullHelpFunc = keys.feeds.FullHelp
 
 	di := list.NewDefaultDelegate()
-	di.ShortHelpFunc = itemsShortHelp
-	di.FullHelpFunc = itemsFullHelp
+	di.ShortHelpFunc = keys.items.ShortHelp
+	di.FullHelpFunc = keys.items.FullHelp
 
 	m := &model{
+		cfg:       cfg,
+		keys:      keys,
 		l:         l,
 		lf:        list.New(nil, df, 0, 0),
 		li:        list.New(nil, di, 0, 0),
@@ -76,6 +160,8 @@ func initialModel() *model {
 		v:         viewport.New(10, 10),
 		vh:        help.New(),
 	}
+	m.urlsMod, m.configMod = configModTimes()
+	m.cacheMod = cacheModTime(l)
 
 	rebuildFeedList(m)
 
@@ -88,37 +174,110 @@ func initialModel() *model {
 
 	if err != nil {
 		m.UpdateStatus(err.Error())
+	} else if problems := urlsWarning(l.Warnings); problems != "" {
+		m.UpdateStatus(problems)
+	}
+
+	if instance, err := l.LockInstance(); err == nil {
+		m.instance = instance
+	} else if errors.Is(err, rss.ErrLocked) {
+		m.UpdateStatus(MsgAnotherInstance)
 	}
 
 	if len(m.l.Feeds) == 0 {
 		m.UpdateStatus(MsgNoFeedsInList)
 	}
 
+	if cfgErr != nil {
+		m.UpdateStatus(cfgErr.Error())
+	}
+
+	if keysErr != nil {
+		m.UpdateStatus(keysErr.Error())
+	}
+
 	return m
 }
 
 func (m *model) Init() tea.Cmd {
-	return nil
+	var cmds []tea.Cmd
+	if m.cfg.Refresh.OnStartup {
+		cmds = append(cmds, updateAllFeedsCmd(m))
+	}
+	cmds = append(cmds, refreshTickCmd(m), watchTickCmd(), eventsCmd(m))
+	return tea.Batch(cmds...)
 }
 
 func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case feedUpdatedMsg:
+		added := 0
+		if msg.Feed != nil && msg.Fetched != nil {
+			added = msg.Feed.MergeCopy(msg.Fetched, msg.Err)
+		}
 		if msg.Err != nil {
 			m.UpdateStatus(fmt.Sprintf("Error updating: %v", msg.Err))
 		} else {
 			m.UpdateStatus(fmt.Sprintf("Updated %s", msg.Feed.Url))
+			if added > 0 && msg.Feed.Options.Notify {
+				notify(msg.Feed.Name(), fmt.Sprintf("%d %s", added, MsgNewItems))
+			}
 		}
+		m.l.RefreshBookmarks()
 		rebuildFeedList(m)
+		if m.f == msg.Feed && m.f != nil {
+			rebuildItemsList(m)
+		}
 		return m, nil
+	case changesPushedMsg:
+		m.pushing = false
+		if msg.Err != nil {
+			// Kept pending for the next change or refresh
+			m.UpdateStatus(fmt.Sprintf("%s: %v", ErrPushingChanges, msg.Err))
+			return m, nil
+		}
+		return m, pushChangesCmd(m)
 	case feedsDoneMsg:
 		m.UpdateStatus(MsgAllFeedsUpdated)
 		return m, nil
 	case statusClearMsg:
 		m.status = ""
 		return m, nil
+	case feedPreviewMsg:
+		return m, handleFeedPreview(m, msg)
+	case daemonEventsMsg:
+		if msg.Err != nil {
+			m.UpdateStatus(fmt.Sprintf("%s: %v", ErrDaemonEvents, msg.Err))
+			return m, nil
+		}
+		return m, applyDaemonChanges(m, msg.Changes)
+	case includesFetchedMsg:
+		// Includes that still fail are reported by reconcileUrls
+		if msg.Err != nil && !errors.Is(msg.Err, rss.ErrInclude) {
+			m.UpdateStatus(msg.Err.Error())
+			return m, nil
+		}
+		added, err := reconcileUrls(m)
+		if err != nil {
+			m.UpdateStatus(err.Error())
+			return m, nil
+		}
+		return m, updateFeedsCmd(m, added)
+	case watchTickMsg:
+		return m, tea.Batch(watchConfigFiles(m), watchTickCmd())
+	case refreshTickMsg:
+		if m.cfg.Refresh.Interval <= 0 {
+			// Turned off in config.yaml since the tick was scheduled
+			return m, nil
+		}
+		m.UpdateStatus(MsgUpdatingAllFeeds)
+		return m, tea.Batch(updateAllFeedsCmd(m), refreshTickCmd(m))
 	case tea.KeyMsg:
-		var handlers map[string]keyHandler
+		if m.onSubmit != nil {
+			return m, updatePrompt(m, msg)
+		}
+
+		var keys *keymap
 		lfState := m.lf.FilterState().String()
 		liState := m.li.FilterState().String()
 
@@ -128,18 +287,18 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		switch {
 		case m.i != nil:
-			handlers = viewKeyHandlers
+			keys = m.keys.article
 		case m.f != nil:
-			handlers = itemKeyHandlers
+			keys = m.keys.items
 		default:
-			handlers = feedKeyHandlers
+			keys = m.keys.feeds
 			if i, err := strconv.Atoi(msg.String()); err == nil {
 				return m, handleTabNumber(m, i)
 			}
 		}
 
-		if handler, ok := handlers[msg.String()]; ok {
-			return m, handler(m)
+		if handler, ok := keys.handler(msg.String()); ok {
+			return m, tea.Batch(handler(m), pushChangesCmd(m))
 		}
 
 	case tea.WindowSizeMsg:
@@ -151,7 +310,7 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.v.Width = msg.Width - vh
 		m.v.Height = msg.Height - vv
 		if m.i != nil {
-			m.v.SetContent(wordwrap.String(m.i.Content(), 80))
+			m.v.SetContent(itemContent(m, m.i))
 		}
 	}
 
@@ -1,27 +1,68 @@
 package tui
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/emilosman/rssboat/internal/config"
+	"github.com/muesli/termenv"
+)
+
+// palette holds a theme's colours. Presets use CompleteColor so 256 and
+// 16 colour terminals get a close colour picked by hand.
+type palette struct {
+	unread, bookmark, error, title, status lipgloss.TerminalColor
+	activeTab, unreadTab, inactiveTab      lipgloss.TerminalColor
+}
+
+var presets = map[string]palette{
+	"dark": {
+		unread:      lipgloss.CompleteColor{TrueColor: "#00cf42", ANSI256: "41", ANSI: "10"},
+		bookmark:    lipgloss.CompleteColor{TrueColor: "#FFFF00", ANSI256: "226", ANSI: "11"},
+		error:       lipgloss.CompleteColor{TrueColor: "#e53636ff", ANSI256: "167", ANSI: "9"},
+		title:       lipgloss.CompleteColor{TrueColor: "#ff4fff", ANSI256: "207", ANSI: "13"},
+		status:      lipgloss.CompleteColor{TrueColor: "#767676", ANSI256: "243", ANSI: "8"},
+		activeTab:   lipgloss.CompleteColor{TrueColor: "#ff4fff", ANSI256: "207", ANSI: "13"},
+		unreadTab:   lipgloss.CompleteColor{TrueColor: "#00cf42", ANSI256: "41", ANSI: "10"},
+		inactiveTab: lipgloss.CompleteColor{TrueColor: "#dfdfdf", ANSI256: "253", ANSI: "7"},
+	},
+	"light": {
+		unread:      lipgloss.CompleteColor{TrueColor: "#007a27", ANSI256: "28", ANSI: "2"},
+		bookmark:    lipgloss.CompleteColor{TrueColor: "#9a6700", ANSI256: "136", ANSI: "3"},
+		error:       lipgloss.CompleteColor{TrueColor: "#c11f1f", ANSI256: "160", ANSI: "1"},
+		title:       lipgloss.CompleteColor{TrueColor: "#a21caf", ANSI256: "127", ANSI: "5"},
+		status:      lipgloss.CompleteColor{TrueColor: "#5c5c5c", ANSI256: "240", ANSI: "8"},
+		activeTab:   lipgloss.CompleteColor{TrueColor: "#a21caf", ANSI256: "127", ANSI: "5"},
+		unreadTab:   lipgloss.CompleteColor{TrueColor: "#007a27", ANSI256: "28", ANSI: "2"},
+		inactiveTab: lipgloss.CompleteColor{TrueColor: "#303030", ANSI256: "236", ANSI: "0"},
+	},
+	"high-contrast": {
+		unread:      lipgloss.CompleteColor{TrueColor: "#00ff00", ANSI256: "46", ANSI: "10"},
+		bookmark:    lipgloss.CompleteColor{TrueColor: "#ffff00", ANSI256: "226", ANSI: "11"},
+		error:       lipgloss.CompleteColor{TrueColor: "#ff0000", ANSI256: "196", ANSI: "9"},
+		title:       lipgloss.CompleteColor{TrueColor: "#00ffff", ANSI256: "51", ANSI: "14"},
+		status:      lipgloss.CompleteColor{TrueColor: "#ffffff", ANSI256: "231", ANSI: "15"},
+		activeTab:   lipgloss.CompleteColor{TrueColor: "#00ffff", ANSI256: "51", ANSI: "14"},
+		unreadTab:   lipgloss.CompleteColor{TrueColor: "#00ff00", ANSI256: "46", ANSI: "10"},
+		inactiveTab: lipgloss.CompleteColor{TrueColor: "#ffffff", ANSI256: "231", ANSI: "15"},
+	},
+}
 
 var (
 	viewStyle = lipgloss.NewStyle().Margin(4, 0)
 
 	listStyle = lipgloss.NewStyle().Margin(3, 0)
 
-	unreadStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#00cf42"))
+	unreadStyle = lipgloss.NewStyle()
 
-	bookmarkStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FFFF00"))
+	bookmarkStyle = lipgloss.NewStyle()
 
-	errorStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#e53636ff"))
+	errorStyle = lipgloss.NewStyle()
 
 	titleStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#ff4fff")).
 			Margin(0, 0, 0, 1)
 
 	statusStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#767676")).
 			Margin(0, 0, 0, 1)
 
 	contentStyle = lipgloss.NewStyle().
@@ -32,15 +73,56 @@ var (
 
 	activeTabStyle = lipgloss.NewStyle().
 			Bold(true).
-			Foreground(lipgloss.Color("#ff4fff")).
 			Margin(0, 1, 0, 1)
 
 	unreadTabStyle = lipgloss.NewStyle().
 			Bold(true).
-			Foreground(lipgloss.Color("#00cf42")).
 			Margin(0, 1, 0, 1)
 
 	inactiveTabStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("#dfdfdf")).
 				Margin(0, 1, 0, 1)
 )
+
+func init() {
+	applyPalette(presets["dark"])
+}
+
+// applyTheme sets the styles from the theme in config.yaml. NO_COLOR turns
+// colours off and leaves bold text to tell tabs apart.
+func applyTheme(theme config.Theme) {
+	if os.Getenv("NO_COLOR") != "" {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+
+	p, ok := presets[theme.Preset]
+	if !ok {
+		p = presets["dark"]
+	}
+
+	override := func(c *lipgloss.TerminalColor, custom config.Color) {
+		if custom != "" {
+			*c = lipgloss.Color(custom)
+		}
+	}
+	override(&p.unread, theme.Colors.Unread)
+	override(&p.bookmark, theme.Colors.Bookmark)
+	override(&p.error, theme.Colors.Error)
+	override(&p.title, theme.Colors.Title)
+	override(&p.status, theme.Colors.Status)
+	override(&p.activeTab, theme.Colors.ActiveTab)
+	override(&p.unreadTab, theme.Colors.UnreadTab)
+	override(&p.inactiveTab, theme.Colors.InactiveTab)
+
+	applyPalette(p)
+}
+
+func applyPalette(p palette) {
+	unreadStyle = unreadStyle.Foreground(p.unread)
+	bookmarkStyle = bookmarkStyle.Foreground(p.bookmark)
+	errorStyle = errorStyle.Foreground(p.error)
+	titleStyle = titleStyle.Foreground(p.title)
+	statusStyle = statusStyle.Foreground(p.status)
+	activeTabStyle = activeTabStyle.Foreground(p.activeTab)
+	unreadTabStyle = unreadTabStyle.Foreground(p.unreadTab)
+	inactiveTabStyle = inactiveTabStyle.Foreground(p.inactiveTab)
+}
@@ -0,0 +1,34 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/emilosman/rssboat/internal/config"
+)
+
+func TestStyles(t *testing.T) {
+	t.Cleanup(func() { applyTheme(config.Default().Theme) })
+
+	t.Run("Should have a palette for every preset", func(t *testing.T) {
+		for _, preset := range config.Presets {
+			if _, ok := presets[preset]; !ok {
+				t.Errorf("Missing palette for %s", preset)
+			}
+		}
+	})
+
+	t.Run("Should apply preset and overrides", func(t *testing.T) {
+		applyTheme(config.Theme{Preset: "light", Colors: config.Colors{Unread: "#123456"}})
+
+		if unreadStyle.GetForeground() != lipgloss.Color("#123456") {
+			t.Errorf("Override not applied, got %v", unreadStyle.GetForeground())
+		}
+		if titleStyle.GetForeground() != presets["light"].title {
+			t.Errorf("Preset not applied, got %v", titleStyle.GetForeground())
+		}
+		if !activeTabStyle.GetBold() || activeTabStyle.GetMarginLeft() != 1 {
+			t.Error("Theme should only change colours")
+		}
+	})
+}
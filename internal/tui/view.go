@@ -9,7 +9,7 @@ func (m *model) View() string {
 		title := renderedTitle(m)
 		status := renderedStatus(m)
 		content := contentStyle.Render(m.v.View())
-		help := helpStyle.Render(m.vh.View(viewKeyMap{}))
+		help := helpStyle.Render(m.vh.View(m.keys.article))
 		view := lipgloss.JoinVertical(lipgloss.Left, title, status, content, help)
 		return viewStyle.Render(view)
 	case m.f != nil: